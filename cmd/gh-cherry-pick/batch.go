@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// batchResult is the outcome of an entry of a batch onto one target.
+type batchResult struct {
+	entry      git.BatchEntry
+	target     string
+	result     git.Result
+	conflicted bool
+	err        error
+}
+
+// runBatch cherry-picks each entry onto its targets, or those of onto, with
+// the options of template. Failures do not stop the batch: a conflicted
+// cherry-pick is aborted, and the report at the end lists every outcome.
+func runBatch(ctx context.Context, template git.CherryPick, entries []git.BatchEntry, onto string) error {
+	logger := log.LoggerFromCtx(ctx)
+
+	var results []batchResult
+	for _, entry := range entries {
+		spec := entry.Onto
+		if spec == "" {
+			spec = onto
+		}
+		if spec == "" {
+			results = append(results, batchResult{entry: entry, target: "(none)", err: errors.New("no target branch. add it to the line or pass -onto")})
+			continue
+		}
+
+		targets, _, err := git.ResolveTargets(ctx, spec)
+		if err != nil {
+			results = append(results, batchResult{entry: entry, target: spec, err: err})
+			continue
+		}
+
+		for _, target := range targets {
+			cherryPick := template
+			cherryPick.PRNumber, cherryPick.Repo = entry.Input.PRNumber, entry.Input.Repo
+			cherryPick.CommitSHA, cherryPick.CommitRange = entry.Input.CommitSHA, entry.Input.CommitRange
+			cherryPick.OnTo = target
+			if entry.MergeStrategy != "" {
+				cherryPick.MergeStrategy = entry.MergeStrategy
+			}
+
+			r := batchResult{entry: entry, target: target}
+			r.err = cherryPick.RunWithContext(ctx)
+			r.result = cherryPick.Result
+
+			var conflictErr *git.ConflictError
+			if r.conflicted = errors.As(r.err, &conflictErr); r.conflicted {
+				logger.Failf(r.err.Error())
+				if err := git.AbortConflicted(git.CtxWithDir(ctx, cherryPick.Result.Dir)); err != nil {
+					logger.WithError(err).Warnf("error aborting the conflicted cherry-pick")
+				}
+			} else if r.err != nil {
+				logger.Failf(r.err.Error())
+			}
+			results = append(results, r)
+		}
+	}
+
+	return reportBatch(logger, results)
+}
+
+// reportBatch logs the outcome of each cherry-pick of the batch, and fails
+// when any of them failed.
+func reportBatch(logger log.Logger, results []batchResult) error {
+	logger.Infof("🍒 %s", color.Bold("report\n"))
+
+	failed := 0
+	for _, r := range results {
+		name := fmt.Sprintf("line %d: %s → %s", r.entry.Line, color.Cyan(r.entry), color.Cyan(r.target))
+		switch {
+		case r.conflicted:
+			failed++
+			logger.Failf("%s: conflicts, aborted", name)
+		case r.err != nil:
+			failed++
+			message, _, _ := strings.Cut(r.err.Error(), "\n")
+			logger.Failf("%s: %s", name, message)
+		case r.result.AlreadyPresent:
+			logger.Successf("%s: already present", name)
+		case r.result.PullRequestURL != "":
+			logger.Successf("%s: %s", name, r.result.PullRequestURL)
+		default:
+			logger.Successf("%s: %s", name, r.result.Branch)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cherry-pick(s) failed", failed, len(results))
+	}
+	return nil
+}
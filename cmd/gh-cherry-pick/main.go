@@ -2,30 +2,154 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/internal/action"
+	"github.com/134130/gh-cherry-pick/internal/githubapp"
 	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/server"
+	"github.com/134130/gh-cherry-pick/internal/tui"
 )
 
 var (
-	prNumber = flag.Int("pr", 0, "The PR number onto cherry-pick (required)")
-	onto     = flag.String("onto", "", "The branch to cherry-pick onto (required)")
-	merge    = flag.String("merge", "auto", "The merge strategy to use (rebase, squash, or auto) (default: auto)")
-	push     = flag.Bool("push", false, "Push the cherry-picked branch to the remote branch")
-	worktree = flag.Bool("worktree", false, "Use a temporary worktree cached in the OS temp directory")
+	fromFile      = flag.String("from-file", "", "A file of PRs to cherry-pick, one per line as 'PR [TARGETS] [STRATEGY]'. -pr - reads them from stdin")
+	search        = flag.String("search", "", "A search query of the merged PRs to cherry-pick in merge order instead of -pr, e.g. \"label:needs-backport base:main\"")
+	prInput       = flag.String("pr", "", "The PR to cherry-pick: a number, URL, owner/repo#number, merge commit SHA or commit range (required)")
+	onto          = flag.String("onto", "", "Comma-separated branches to cherry-pick onto, globs like release/*, latest:N or supported (required)")
+	ontoTag       = flag.String("onto-tag", "", "The tag or ref to create the -create-branch target branch from when it does not exist")
+	createBranch  = flag.String("create-branch", "", "The target branch to create on origin from -onto-tag, or from the commit of -onto, when it does not exist")
+	merge         = flag.String("merge", "auto", "The merge strategy to use (rebase, squash, or auto) (default: auto)")
+	push          = flag.Bool("push", false, "Push the cherry-picked branch to the remote branch")
+	worktree      = flag.Bool("worktree", false, "Use a temporary worktree cached in the OS temp directory")
+	allowUnmerged = flag.Bool("allow-unmerged", false, "Cherry-pick the commits of a PR which is not merged")
+	commits       = flag.String("commits", "", "Comma-separated commit SHAs or 1-based indexes (e.g. 1,3-4) of the PR to cherry-pick")
+	include       = flag.String("include", "", "Comma-separated path globs to cherry-pick; other paths are left out")
+	exclude       = flag.String("exclude", "", "Comma-separated path globs to leave out of the cherry-pick")
+	autoStash     = flag.Bool("autostash", false, "Stash local changes before cherry-picking and restore them afterwards")
+	keepOnFailure = flag.Bool("keep-on-failure", false, "Keep the cherry-pick branch checked out when cherry-picking fails or is interrupted")
+	createPR      = flag.Bool("create-pr", false, "Push the cherry-picked branch and create a pull request")
+	copyMetadata  = flag.Bool("copy-metadata", true, "Copy the labels, milestone, assignees, reviewers and projects of the PR to the created pull request")
+	dropLabels    = flag.String("drop-labels", "backport *", "Comma-separated globs of the labels not to copy to the created pull request")
+	addLabels     = flag.String("add-labels", "", "Comma-separated labels to add to the created pull request")
+	assignAuthor  = flag.Bool("assign-author", true, "Assign the author of the PR to the created pull request")
+	onConflict    = flag.String("on-conflict", "stop", "What to do on conflicts: stop to resolve them locally, or draft-pr to push them as a draft pull request")
+	comment       = flag.Bool("comment", false, "Post or update a comment on the PR with the result of each target")
+	includePrereq = flag.Bool("include-prerequisites", false, "On conflicts, cherry-pick the missing PRs which changed the conflicting lines first, and retry")
+	includeFollow = flag.Bool("include-follow-ups", false, "Cherry-pick the later commits of the base branch which refer to the PR on top of it")
+	yes           = flag.Bool("yes", false, "Do not ask to confirm the branches which the -onto patterns resolve to, or the PRs which -search finds")
+	cascade       = flag.Bool("cascade", false, "Backport onto the comma-separated -onto branches newest first, each from the backport onto the previous one")
+	recordOrigin  = flag.Bool("record-origin", true, "Append the '(cherry picked from commit <sha>)' trailer of git cherry-pick -x to the cherry-picked commits")
+	jobs          = flag.Int("jobs", 1, "The number of targets cherry-picked at the same time, each in its own temporary worktree")
+	resolvers     git.Resolvers
 )
 
 func main() {
-	flag.Parse()
-	if *prNumber == 0 || *onto == "" {
+	args := os.Args[1:]
+	// forward-port takes the flags of a backport, onto a newer line.
+	var forwardPort bool
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "forward-port":
+			forwardPort, args = true, args[1:]
+		case "abort":
+			run(git.Abort)
+			return
+		case "continue":
+			run(git.ContinueCascade)
+			return
+		case "revert":
+			runRevert(os.Args[2:])
+			return
+		case "action":
+			runAction(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Var(&resolvers, "resolve", "Resolve the conflicts of matching paths automatically, as GLOB=STRATEGY where STRATEGY is ours, theirs, union or run:COMMAND (repeatable)")
+	_ = flag.CommandLine.Parse(args)
+	// A batch is read from -from-file, or from stdin with -pr -. Its lines
+	// may name their own targets.
+	batch := *fromFile != "" || *prInput == "-"
+	if (*prInput == "" && *search == "" && !batch) || (*onto == "" && *ontoTag == "" && !batch) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if sources := countSet(*prInput, *search, *fromFile); sources > 1 {
+		fmt.Fprintln(os.Stderr, "only one of -pr, -search and -from-file can be used")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *jobs < 1 || (*jobs > 1 && *cascade) {
+		fmt.Fprintln(os.Stderr, "-jobs must be at least 1, and -cascade backports onto one branch at a time")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *cascade && *worktree {
+		// A paused cascade is resumed from the repository it was saved in.
+		fmt.Fprintln(os.Stderr, "-cascade cannot be used with -worktree, as gh cherry-pick continue resumes it in the current repository")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if batch && *cascade {
+		fmt.Fprintln(os.Stderr, "-cascade cannot be used with a batch")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// -create-branch makes the target branch from -onto-tag, or from the tag
+	// or commit given to -onto.
+	targetSpec, startPoint := *onto, ""
+	if *createBranch != "" {
+		targetSpec, startPoint = *createBranch, *ontoTag
+		if startPoint == "" {
+			startPoint = *onto
+		}
+		if (*ontoTag != "" && *onto != "") || strings.Contains(startPoint, ",") {
+			fmt.Fprintln(os.Stderr, "-create-branch creates one branch from either -onto-tag or -onto")
+			flag.Usage()
+			os.Exit(2)
+		}
+	} else if *ontoTag != "" {
+		fmt.Fprintln(os.Stderr, "-onto-tag needs -create-branch to name the target branch")
 		flag.Usage()
 		os.Exit(2)
 	}
 
+	var entries []git.BatchEntry
+	if batch {
+		var err error
+		if entries, err = readBatch(*fromFile); err == nil && len(entries) == 0 {
+			err = errors.New("the batch has no PRs")
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	var input git.Input
+	if *prInput != "" && !batch {
+		var err error
+		if input, err = git.ParseInput(*prInput); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+
 	mergeStrategy := git.MergeStrategy(*merge)
 	if err := mergeStrategy.Validate(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -33,20 +157,351 @@ func main() {
 		os.Exit(2)
 	}
 
+	conflictAction := git.ConflictAction(*onConflict)
+	if err := conflictAction.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *cascade && input.CommitRange != "" {
+		fmt.Fprintln(os.Stderr, "-cascade backports a PR, not a commit range")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	commitSelection, err := git.ParseCommitSelection(*commits)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	cherryPick := git.CherryPick{
-		PRNumber:      *prNumber,
-		OnTo:          *onto,
+		PRNumber:      input.PRNumber,
+		Repo:          input.Repo,
+		CommitSHA:     input.CommitSHA,
+		CommitRange:   input.CommitRange,
+		StartPoint:    startPoint,
 		MergeStrategy: mergeStrategy,
 		Push:          *push,
 		Worktree:      *worktree,
+		AllowUnmerged: *allowUnmerged,
+		Commits:       commitSelection,
+		Paths:         git.ParsePathFilter(*include, *exclude),
+		AutoStash:     *autoStash,
+		KeepOnFailure: *keepOnFailure,
+		CreatePR:      *createPR,
+		Metadata: git.MetadataOptions{
+			Copy:         *copyMetadata,
+			DropLabels:   git.SplitList(*dropLabels),
+			AddLabels:    git.SplitList(*addLabels),
+			AssignAuthor: *assignAuthor,
+		},
+		Resolvers:            resolvers,
+		IncludePrerequisites: *includePrereq,
+		IncludeFollowUps:     *includeFollow,
+		OnConflict:           conflictAction,
+		Comment:              *comment,
+		ForwardPort:          forwardPort,
+		RecordOrigin:         *recordOrigin,
+	}
+
+	run(func(ctx context.Context) error {
+		if batch {
+			return runBatch(ctx, cherryPick, entries, targetSpec)
+		}
+
+		targets, err := resolveTargets(ctx, targetSpec, !*yes)
+		if err != nil {
+			return err
+		}
+		if *search == "" {
+			return backport(ctx, cherryPick, targets)
+		}
+
+		prs, err := searchPRs(ctx, *search, !*yes)
+		if err != nil {
+			return err
+		}
+		for _, pr := range prs {
+			cherryPick := cherryPick
+			cherryPick.PRNumber = pr.Number
+			if err := backport(ctx, cherryPick, targets); err != nil {
+				return fmt.Errorf("error cherry-picking #%d: %w", pr.Number, err)
+			}
+		}
+		return nil
+	})
+}
+
+// countSet returns how many of the values are set.
+func countSet(values ...string) int {
+	n := 0
+	for _, value := range values {
+		if value != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// readBatch parses the batch of the file, or of stdin when file is empty.
+func readBatch(file string) ([]git.BatchEntry, error) {
+	if file == "" {
+		return git.ParseBatch(os.Stdin)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the batch: %w", err)
+	}
+	defer f.Close()
+
+	entries, err := git.ParseBatch(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return entries, nil
+}
+
+// backport cherry-picks onto each target, one after the other, or -jobs of
+// them at the same time, or cascades through them with -cascade.
+func backport(ctx context.Context, cherryPick git.CherryPick, targets []string) error {
+	if *cascade {
+		return git.NewCascade(cherryPick, targets).RunWithContext(ctx)
+	}
+	if len(targets) == 1 {
+		cherryPick.OnTo = targets[0]
+		return cherryPick.RunWithContext(ctx)
+	}
+	if *jobs > 1 {
+		parallel := git.Parallel{CherryPick: cherryPick, Targets: targets, Jobs: *jobs}
+		return parallel.RunWithContext(ctx)
+	}
+
+	for _, target := range targets {
+		cherryPick := cherryPick
+		cherryPick.OnTo = target
+		if err := cherryPick.RunWithContext(ctx); err != nil {
+			return fmt.Errorf("error cherry-picking onto %s: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// searchPRs resolves the -search query to the merged PRs in merge order. In
+// interactive mode, it asks to confirm them.
+func searchPRs(ctx context.Context, query string, confirm bool) ([]git.SearchResult, error) {
+	repo, err := git.GetRepository(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the current repository: %w", err)
 	}
 
+	prs, err := git.SearchPullRequests(ctx, repo, query)
+	if err != nil {
+		return nil, err
+	}
+	if !confirm || !tui.IsInteractive() {
+		return prs, nil
+	}
+
+	if ok, err := tui.Confirm(fmt.Sprintf("cherry-pick these %d PR(s)?", len(prs))); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("cancelled")
+	}
+	return prs, nil
+}
+
+// resolveTargets resolves the -onto specs into branches. In interactive
+// mode, it asks to confirm those which the patterns resolve to.
+func resolveTargets(ctx context.Context, onto string, confirm bool) ([]string, error) {
+	targets, expanded, err := git.ResolveTargets(ctx, onto)
+	if err != nil {
+		return nil, err
+	}
+	if !expanded || !confirm || !tui.IsInteractive() {
+		return targets, nil
+	}
+
+	if ok, err := tui.Confirm(fmt.Sprintf("continue with these %d branch(es)?", len(targets))); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("cancelled")
+	}
+	return targets, nil
+}
+
+func runRevert(args []string) {
+	flags := flag.NewFlagSet("revert", flag.ExitOnError)
+	prInput := flags.String("pr", "", "The merged PR whose backports to revert: a number, URL, owner/repo#number or merge commit SHA (required)")
+	onto := flags.String("onto", "", "Comma-separated branches to revert the backport on, globs like release/*, latest:N or supported (required)")
+	push := flags.Bool("push", false, "Push the revert branch to the remote branch")
+	worktree := flags.Bool("worktree", false, "Use a temporary worktree cached in the OS temp directory")
+	autoStash := flags.Bool("autostash", false, "Stash local changes before reverting and restore them afterwards")
+	keepOnFailure := flags.Bool("keep-on-failure", false, "Keep the revert branch checked out when reverting fails or is interrupted")
+	createPR := flags.Bool("create-pr", true, "Push the revert branch and create a pull request")
+	yes := flags.Bool("yes", false, "Do not ask to confirm the branches which the -onto patterns resolve to")
+	_ = flags.Parse(args)
+
+	input, err := git.ParseInput(*prInput)
+	if err == nil && input.CommitRange != "" {
+		err = errors.New("a commit range has no backports to revert, use a PR")
+	}
+	if err == nil && *onto == "" {
+		err = errors.New("-onto is required")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	run(func(ctx context.Context) error {
+		targets, err := resolveTargets(ctx, *onto, !*yes)
+		if err != nil {
+			return err
+		}
+
+		for _, target := range targets {
+			revert := git.CherryPick{
+				PRNumber:      input.PRNumber,
+				Repo:          input.Repo,
+				CommitSHA:     input.CommitSHA,
+				OnTo:          target,
+				Push:          *push,
+				Worktree:      *worktree,
+				AutoStash:     *autoStash,
+				KeepOnFailure: *keepOnFailure,
+				CreatePR:      *createPR,
+				Revert:        true,
+			}
+			if err := revert.RunWithContext(ctx); err != nil {
+				return fmt.Errorf("error reverting the backport on %s: %w", target, err)
+			}
+		}
+		return nil
+	})
+}
+
+func runAction(args []string) {
+	flags := flag.NewFlagSet("action", flag.ExitOnError)
+	labelPrefix := flags.String("label-prefix", "backport ", "The prefix of the labels which name the target branches")
+	merge := flags.String("merge", "auto", "The merge strategy to use (rebase, squash, or auto) (default: auto)")
+	onConflict := flags.String("on-conflict", "stop", "What to do on conflicts: stop to comment on the PR, or draft-pr to push them as a draft pull request")
+	appID, appKey := appFlags(flags)
+	_ = flags.Parse(args)
+
+	mergeStrategy := git.MergeStrategy(*merge)
+	if err := mergeStrategy.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	conflictAction := git.ConflictAction(*onConflict)
+	if err := conflictAction.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	run(func(ctx context.Context) error {
+		app, err := loadApp(*appID, *appKey)
+		if err != nil {
+			return err
+		}
+		return action.Run(ctx, action.Options{LabelPrefix: *labelPrefix, MergeStrategy: mergeStrategy, OnConflict: conflictAction, App: app})
+	})
+}
+
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "The address to listen on")
+	secret := flags.String("secret", os.Getenv("GH_CHERRY_PICK_WEBHOOK_SECRET"), "The webhook secret (default: $GH_CHERRY_PICK_WEBHOOK_SECRET)")
+	dataDir := flags.String("data-dir", filepath.Join(os.TempDir(), "gh-cherry-pick", "serve"), "The directory of the job queue, clones and worktrees")
+	workers := flags.Int("workers", 2, "The number of backports run at the same time")
+	labelPrefix := flags.String("label-prefix", "backport ", "The prefix of the labels which name the target branches")
+	merge := flags.String("merge", "auto", "The merge strategy to use (rebase, squash, or auto) (default: auto)")
+	onConflict := flags.String("on-conflict", "stop", "What to do on conflicts: stop to comment on the PR, or draft-pr to push them as a draft pull request")
+	appID, appKey := appFlags(flags)
+	_ = flags.Parse(args)
+
+	mergeStrategy := git.MergeStrategy(*merge)
+	if err := mergeStrategy.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	conflictAction := git.ConflictAction(*onConflict)
+	if err := conflictAction.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	run(func(ctx context.Context) error {
+		dir, err := filepath.Abs(*dataDir)
+		if err != nil {
+			return fmt.Errorf("error resolving the data directory: %w", err)
+		}
+
+		app, err := loadApp(*appID, *appKey)
+		if err != nil {
+			return err
+		}
+
+		s, err := server.New(server.Config{
+			Addr:          *addr,
+			Secret:        *secret,
+			DataDir:       dir,
+			Workers:       *workers,
+			LabelPrefix:   *labelPrefix,
+			MergeStrategy: mergeStrategy,
+			OnConflict:    conflictAction,
+			App:           app,
+		})
+		if err != nil {
+			return err
+		}
+		return s.Run(ctx)
+	})
+}
+
+// appFlags defines the flags of the GitHub App to authenticate as.
+func appFlags(flags *flag.FlagSet) (id *int64, keyPath *string) {
+	defaultID, _ := strconv.ParseInt(os.Getenv("GH_CHERRY_PICK_APP_ID"), 10, 64)
+	id = flags.Int64("app-id", defaultID, "The ID of the GitHub App to authenticate as (default: $GH_CHERRY_PICK_APP_ID)")
+	keyPath = flags.String("app-key", os.Getenv("GH_CHERRY_PICK_APP_KEY"), "The private key file of the GitHub App (default: $GH_CHERRY_PICK_APP_KEY)")
+	return id, keyPath
+}
+
+// loadApp returns the GitHub App on the host of GH_HOST or GITHUB_SERVER_URL,
+// or nil when no app is configured.
+func loadApp(id int64, keyPath string) (*githubapp.App, error) {
+	if id == 0 && keyPath == "" {
+		return nil, nil
+	}
+	if id == 0 || keyPath == "" {
+		return nil, errors.New("both -app-id and -app-key are required to authenticate as a GitHub App")
+	}
+
+	host := os.Getenv("GH_HOST")
+	if serverURL, err := url.Parse(os.Getenv("GITHUB_SERVER_URL")); host == "" && err == nil {
+		host = serverURL.Host
+	}
+	return githubapp.Load(host, id, keyPath)
+}
+
+func run(f func(ctx context.Context) error) {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
 	ctx = log.CtxWithLogger(ctx)
 
-	if err := cherryPick.RunWithContext(ctx); err != nil {
+	if err := f(ctx); err != nil {
 		log.LoggerFromCtx(ctx).Failf(err.Error())
 		os.Exit(1)
 	}
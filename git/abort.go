@@ -0,0 +1,137 @@
+package git
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+// Abort aborts the unfinished cherry-pick recorded in the state. It aborts
+// an in-progress git am, git cherry-pick or git revert, switches back to the original
+// branch, deletes the cherry-pick branch and restores the stashed changes.
+func Abort(ctx context.Context) error {
+	state, err := LoadState(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading the state: %w", err)
+	} else if state == nil {
+		return fmt.Errorf("no cherry-pick in progress")
+	}
+
+	return tui.WithStep(ctx, "aborting cherry-pick", func(ctx context.Context, logger log.Logger) error {
+		if err := restoreState(ctx, logger, state, false); err != nil {
+			return err
+		}
+		// Aborting a backport of a cascade stops the cascade.
+		return removeCascade(ctx)
+	})
+}
+
+// AbortConflicted aborts the cherry-pick which stopped on conflicts in the
+// repository of ctx. Without a state, as in the cache of Worktree, it aborts
+// the git operation in progress and discards the changes instead, so that
+// the next cherry-pick can check out its branch there.
+func AbortConflicted(ctx context.Context) error {
+	if state, err := LoadState(ctx); err != nil {
+		return fmt.Errorf("error loading the state: %w", err)
+	} else if state != nil {
+		return Abort(ctx)
+	}
+
+	return tui.WithStep(ctx, "aborting cherry-pick", func(ctx context.Context, logger log.Logger) error {
+		if err := abortOperation(ctx, logger); err != nil {
+			return err
+		}
+
+		logger.Infof("discarding changes")
+		if err := NewCommand("git", "reset", "--hard").Run(ctx); err != nil {
+			return fmt.Errorf("error discarding changes: %w", err)
+		}
+		return nil
+	})
+}
+
+// restoreState switches back to the original branch of the state and pops
+// its stash. The cherry-pick branch is deleted unless keepBranch is set.
+func restoreState(ctx context.Context, logger log.Logger, state *State, keepBranch bool) error {
+	if !keepBranch {
+		if err := abortOperation(ctx, logger); err != nil {
+			return err
+		}
+
+		if currentBranch, err := GetCurrentBranch(ctx); err == nil && currentBranch == state.Branch {
+			logger.WithField("branch", state.Branch).Infof("discarding changes")
+			if err = NewCommand("git", "reset", "--hard").Run(ctx); err != nil {
+				return fmt.Errorf("error discarding changes of branch %s: %w", state.Branch, err)
+			}
+		}
+	}
+
+	logger.WithField("ref", state.OriginalRef()).Infof("switching back")
+	if err := Switch(ctx, state.OriginalRef()); err != nil {
+		return fmt.Errorf("error switching back to %s: %w", state.OriginalRef(), err)
+	}
+
+	if branchHead, err := RevParse(ctx, "refs/heads/"+state.Branch); err == nil && !keepBranch {
+		logger.WithField("branch", state.Branch).WithField("was", branchHead[:7]).Infof("deleting the cherry-pick branch")
+		if err = DeleteBranch(ctx, state.Branch); err != nil {
+			return fmt.Errorf("error deleting branch %s: %w", state.Branch, err)
+		}
+	}
+
+	if state.StashRef != "" {
+		logger.WithField("stash", state.StashRef[:7]).Infof("restoring the stashed changes")
+		if err := StashPop(ctx, state.StashRef); err != nil {
+			return fmt.Errorf("error restoring the stashed changes. run %s to restore them manually: %w", color.Yellow(fmt.Sprintf("`git stash apply %s`", state.StashRef)), err)
+		}
+	}
+
+	if err := RemoveState(ctx); err != nil {
+		return fmt.Errorf("error removing the state: %w", err)
+	}
+
+	logger.Successf("back on %s", color.Cyan(state.OriginalRef()))
+	return nil
+}
+
+// abortOperation aborts the git am, git cherry-pick or git revert in
+// progress, if any.
+func abortOperation(ctx context.Context, logger log.Logger) error {
+	if rebaseOrAm, err := IsInRebaseOrAm(ctx); err != nil {
+		return fmt.Errorf("error checking if the repository is in a rebase or am: %w", err)
+	} else if rebaseOrAm {
+		// A rebase is left by noting a forward-port in the commit messages.
+		operation := "rebase"
+		if inAm, err := IsInAm(ctx); err != nil {
+			return fmt.Errorf("error checking if the repository is in an am: %w", err)
+		} else if inAm {
+			operation = "am"
+		}
+
+		logger.Infof("aborting git %s", operation)
+		if err = NewCommand("git", operation, "--abort").Run(ctx); err != nil {
+			return fmt.Errorf("error aborting git %s: %w", operation, err)
+		}
+	}
+
+	if inCherryPick, err := IsInCherryPick(ctx); err != nil {
+		return fmt.Errorf("error checking if the repository is in a cherry-pick: %w", err)
+	} else if inCherryPick {
+		logger.Infof("aborting git cherry-pick")
+		if err = NewCommand("git", "cherry-pick", "--abort").Run(ctx); err != nil {
+			return fmt.Errorf("error aborting git cherry-pick: %w", err)
+		}
+	}
+
+	if inRevert, err := IsInRevert(ctx); err != nil {
+		return fmt.Errorf("error checking if the repository is in a revert: %w", err)
+	} else if inRevert {
+		logger.Infof("aborting git revert")
+		if err = NewCommand("git", "revert", "--abort").Run(ctx); err != nil {
+			return fmt.Errorf("error aborting git revert: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package git
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+func TestAbortOperation(t *testing.T) {
+	testcases := []struct {
+		name string
+		// stop leaves an operation in progress.
+		stop string
+	}{{
+		name: "rebase",
+		stop: "git checkout -q feature && (git rebase --exec false main || true)",
+	}, {
+		name: "am",
+		stop: "git checkout -q release && printf 'x\\n' > a.txt && git commit -qam x && (git format-patch -1 --stdout feature~1 | git am || true)",
+	}, {
+		name: "cherry-pick",
+		stop: "git checkout -q release && printf 'x\\n' > a.txt && git commit -qam x && (git cherry-pick feature~1 || true)",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, sh := newTestRepo(t)
+			sh(tc.stop)
+			if rebaseOrAm, _ := IsInRebaseOrAm(ctx); !rebaseOrAm {
+				if inCherryPick, _ := IsInCherryPick(ctx); !inCherryPick {
+					t.Fatalf("expected the %s to stop", tc.name)
+				}
+			}
+
+			if err := abortOperation(ctx, log.NewLogger()); err != nil {
+				t.Fatal(err)
+			}
+			if rebaseOrAm, err := IsInRebaseOrAm(ctx); err != nil || rebaseOrAm {
+				t.Errorf("expected no rebase or am, got %v, %v", rebaseOrAm, err)
+			}
+			if inCherryPick, err := IsInCherryPick(ctx); err != nil || inCherryPick {
+				t.Errorf("expected no cherry-pick, got %v, %v", inCherryPick, err)
+			}
+		})
+	}
+}
+
+func TestAbortConflicted(t *testing.T) {
+	testcases := []struct {
+		name string
+		// withState saves a state before each cherry-pick, as it is saved
+		// out of Worktree.
+		withState bool
+	}{{
+		name:      "with a state",
+		withState: true,
+	}, {
+		name: "without a state",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, sh := newTestRepo(t)
+			sh("git checkout -q release && printf 'x\\n' > a.txt && git commit -qam x && git checkout -q main")
+
+			// A batch of a conflicting cherry-pick followed by a clean one.
+			for i, commit := range []string{"feature~1", "feature"} {
+				branch := fmt.Sprintf("pick-%d", i)
+				if tc.withState {
+					state, err := NewState(ctx, branch)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if err = state.Save(ctx); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				sh("git switch -qc " + branch + " release")
+				if err := NewCommand("git", "cherry-pick", commit).Run(ctx); err == nil {
+					continue
+				} else if i > 0 {
+					t.Fatalf("expected the cherry-pick of %s to apply: %v", commit, err)
+				}
+
+				if err := AbortConflicted(ctx); err != nil {
+					t.Fatal(err)
+				}
+				if inCherryPick, err := IsInCherryPick(ctx); err != nil || inCherryPick {
+					t.Errorf("expected no cherry-pick, got %v, %v", inCherryPick, err)
+				}
+				if dirty, err := IsDirty(ctx); err != nil || dirty {
+					t.Errorf("expected a clean repository, got %v, %v", dirty, err)
+				}
+			}
+
+			if subject := sh("git log -1 --format=%s pick-1"); subject != "add b" {
+				t.Errorf("expected the second cherry-pick to apply, got %q", subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Auth authenticates gh and git as someone else than the user gh is logged
+// in as, e.g. a GitHub App installation.
+type Auth interface {
+	// Token returns a valid token, refreshing it if needed.
+	Token(ctx context.Context) (string, error)
+	// Identity returns the name and email to commit with, or empty strings to
+	// keep the configured identity.
+	Identity(ctx context.Context) (name, email string)
+}
+
+type authKey struct{}
+
+// CtxWithAuth makes the commands run with the returned context authenticate
+// with auth.
+func CtxWithAuth(ctx context.Context, auth Auth) context.Context {
+	return context.WithValue(ctx, authKey{}, auth)
+}
+
+func authFromCtx(ctx context.Context) Auth {
+	auth, _ := ctx.Value(authKey{}).(Auth)
+	return auth
+}
+
+// tokenEnv is the variable the git credential helper reads the token from,
+// so that the token does not show up in the command line.
+const tokenEnv = "GH_CHERRY_PICK_TOKEN"
+
+// authEnv returns the environment which makes cmd authenticate with auth. gh
+// reads the token from GH_TOKEN, or GH_ENTERPRISE_TOKEN on GitHub Enterprise
+// Server. git gets a credential helper in place of the configured ones, so
+// that pushes over HTTPS use the token too. The authorization header that
+// actions/checkout persists in the config is reset, since git would send it
+// instead of asking the credential helper.
+func authEnv(ctx context.Context, cmd string, auth Auth) ([]string, error) {
+	token, err := auth.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the token: %w", err)
+	}
+
+	switch cmd {
+	case "gh":
+		return []string{"GH_TOKEN=" + token, "GH_ENTERPRISE_TOKEN=" + token}, nil
+	case "git":
+		config := [][2]string{
+			{"credential.helper", ""},
+			{"credential.helper", fmt.Sprintf("!f() { echo username=x-access-token; echo \"password=$%s\"; }; f", tokenEnv)},
+			// An empty value resets the headers of the same URL.
+			{"http.extraheader", ""},
+			{fmt.Sprintf("http.%s/.extraheader", serverURL()), ""},
+		}
+		if name, email := auth.Identity(ctx); name != "" && email != "" {
+			config = append(config, [2]string{"user.name", name}, [2]string{"user.email", email})
+		}
+
+		env := []string{tokenEnv + "=" + token, fmt.Sprintf("GIT_CONFIG_COUNT=%d", len(config))}
+		for i, kv := range config {
+			env = append(env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, kv[0]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, kv[1]))
+		}
+		return env, nil
+	}
+	return nil, nil
+}
+
+// serverURL returns the URL of the GitHub server the workflow runs on, which
+// actions/checkout persists the authorization header for.
+func serverURL() string {
+	if serverURL := os.Getenv("GITHUB_SERVER_URL"); serverURL != "" {
+		return strings.TrimSuffix(serverURL, "/")
+	}
+	return "https://github.com"
+}
@@ -0,0 +1,78 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BatchEntry is a line of a batch of cherry-picks, e.g. "123 release/1.2
+// squash".
+type BatchEntry struct {
+	// Line is the line number of the entry, for the report.
+	Line  int
+	Input Input
+	// Onto are the target specs of the entry, which override -onto when set.
+	Onto string
+	// MergeStrategy overrides -merge when set.
+	MergeStrategy MergeStrategy
+}
+
+// ParseBatch parses a batch with one entry per line: a PR as accepted by
+// ParseInput, optionally followed by the target branches and a merge
+// strategy. Blank lines, and lines starting with # not followed by a PR
+// number, are skipped.
+func ParseBatch(r io.Reader) ([]BatchEntry, error) {
+	var entries []BatchEntry
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || isBatchComment(line) {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		input, err := ParseInput(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		entry := BatchEntry{Line: n, Input: input}
+		for _, field := range fields[1:] {
+			if strategy := MergeStrategy(field); strategy.Validate() == nil && entry.MergeStrategy == "" {
+				entry.MergeStrategy = strategy
+			} else if entry.Onto == "" {
+				entry.Onto = field
+			} else {
+				return nil, fmt.Errorf("line %d: unexpected %q after the target %s", n, field, entry.Onto)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the batch: %w", err)
+	}
+	return entries, nil
+}
+
+// isBatchComment reports whether a line is a comment, which starts with #
+// like "# release 1.2", unlike "#123".
+func isBatchComment(line string) bool {
+	rest, ok := strings.CutPrefix(line, "#")
+	return ok && (rest == "" || rest[0] < '0' || rest[0] > '9')
+}
+
+// String returns the entry as given on its line.
+func (e BatchEntry) String() string {
+	switch {
+	case e.Input.CommitRange != "":
+		return e.Input.CommitRange
+	case e.Input.PRNumber != 0 && e.Input.Repo != nil:
+		return fmt.Sprintf("%s#%d", e.Input.Repo.NameWithOwner(), e.Input.PRNumber)
+	case e.Input.PRNumber != 0:
+		return fmt.Sprintf("#%d", e.Input.PRNumber)
+	default:
+		return e.Input.CommitSHA
+	}
+}
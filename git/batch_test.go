@@ -0,0 +1,47 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+func TestParseBatch(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected []BatchEntry
+		wantErr  bool
+	}{{
+		name:  "PRs with targets and strategies",
+		input: "#123\n\n# release 1.2\n124 release/1.2\nhttps://github.com/owner/repo/pull/125 release/1.1,release/1.0 squash\n126 rebase\n",
+		expected: []BatchEntry{
+			{Line: 1, Input: Input{PRNumber: 123}},
+			{Line: 4, Input: Input{PRNumber: 124}, Onto: "release/1.2"},
+			{Line: 5, Input: Input{Repo: &gitobj.Repository{Host: "github.com", Owner: "owner", Name: "repo"}, PRNumber: 125}, Onto: "release/1.1,release/1.0", MergeStrategy: MergeStrategySquash},
+			{Line: 6, Input: Input{PRNumber: 126}, MergeStrategy: MergeStrategyRebase},
+		},
+	}, {
+		name:    "invalid PR",
+		input:   "123\nfoo release/1.2\n",
+		wantErr: true,
+	}, {
+		name:    "two targets",
+		input:   "123 release/1.2 release/1.1\n",
+		wantErr: true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ParseBatch(strings.NewReader(tc.input))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// PrepareCache returns a clone of the repository cached in the OS temp
+// directory, cloning it from remoteURL on first use.
+func PrepareCache(ctx context.Context, logger log.Logger, repo gitobj.Repository, remoteURL string) (string, error) {
+	ownerDir := filepath.Join(os.TempDir(), "gh-cherry-pick", repo.Owner)
+	cacheDir := filepath.Join(ownerDir, repo.Name)
+
+	if err := os.MkdirAll(ownerDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(cacheDir, ".git")); os.IsNotExist(statErr) {
+		logger.Infof("cloning repository to cache: %s", cacheDir)
+		if err := Clone(ctx, remoteURL, cacheDir); err != nil {
+			return "", fmt.Errorf("error cloning repository: %w", err)
+		}
+	} else {
+		logger.Infof("using cached repository: %s", cacheDir)
+	}
+
+	return cacheDir, nil
+}
@@ -0,0 +1,277 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+const cascadeStateFileName = "gh-cherry-pick-cascade.json"
+
+// Cascade backports a PR onto a chain of release branches, newest first.
+// The branches after the first get the commits of the backport onto the
+// previous one instead of those of the PR, so that the conflicts resolved on
+// a newer line are carried down to the older ones.
+//
+// A backport which stops on conflicts pauses the cascade. It is saved in the
+// git directory, and ContinueCascade resumes it once they are resolved.
+type Cascade struct {
+	// CherryPick is the backport onto each target, whose OnTo is set per target.
+	CherryPick CherryPick `json:"cherryPick"`
+	// Targets are the branches left to backport onto, newest first.
+	Targets []string `json:"targets"`
+	// Previous is the last target backported onto, and Source are the
+	// commits of its backport, which the next target gets.
+	Previous string   `json:"previous,omitempty"`
+	Source   []string `json:"source,omitempty"`
+	// Branch is the backport branch of the first target, which stopped on
+	// conflicts.
+	Branch string `json:"branch,omitempty"`
+}
+
+// NewCascade returns the cascade of the backport onto the targets, sorted
+// newest first. Branches without a version, like the default branch, come
+// first.
+func NewCascade(cherryPick CherryPick, targets []string) *Cascade {
+	targets = slices.Clone(targets)
+	sortNewestFirst(targets)
+	return &Cascade{CherryPick: cherryPick, Targets: targets}
+}
+
+func (cascade *Cascade) RunWithContext(ctx context.Context) error {
+	logger := log.LoggerFromCtx(ctx)
+
+	chain := make([]string, 0, len(cascade.Targets))
+	for _, target := range cascade.Targets {
+		chain = append(chain, color.Cyan(target))
+	}
+	logger.Infof("🍒 cascading %s", strings.Join(chain, " → "))
+
+	for len(cascade.Targets) > 0 {
+		cherryPick := cascade.CherryPick
+		cherryPick.OnTo = cascade.Targets[0]
+		cherryPick.cascadeFrom, cherryPick.cascadeCommits = cascade.Previous, cascade.Source
+
+		err := cherryPick.RunWithContext(ctx)
+		// The PR of a merge commit is resolved by the first backport.
+		cascade.CherryPick.PRNumber = cherryPick.PRNumber
+
+		var conflictErr *ConflictError
+		if errors.As(err, &conflictErr) {
+			cascade.Branch = cherryPick.Result.Branch
+			if saveErr := cascade.save(CtxWithDir(ctx, cherryPick.Result.Dir)); saveErr != nil {
+				return fmt.Errorf("%w\n\nerror saving the cascade: %w", err, saveErr)
+			}
+			return fmt.Errorf("%w\n\nthen run %s to backport the resolved changes onto %s", err, color.Green("`gh cherry-pick continue`"), strings.Join(cascade.Targets[1:], ", "))
+		} else if err != nil {
+			return fmt.Errorf("error backporting onto %s: %w", cherryPick.OnTo, err)
+		}
+		if cherryPick.Result.Draft {
+			return fmt.Errorf("the conflicts of the backport onto %s are pushed as a draft pull request. the cascade stops, as the older lines need their resolution", cherryPick.OnTo)
+		}
+
+		cascade.next(&cherryPick)
+	}
+	return nil
+}
+
+// next moves on from the first target, whose backport is done. When the
+// target has the changes already, the next one gets those of the PR.
+func (cascade *Cascade) next(cherryPick *CherryPick) {
+	if cherryPick.Result.AlreadyPresent {
+		cascade.Previous, cascade.Source = "", nil
+	} else {
+		cascade.Previous, cascade.Source = cherryPick.OnTo, cherryPick.Result.Commits
+	}
+	cascade.Targets = cascade.Targets[1:]
+	cascade.Branch = ""
+}
+
+// ContinueCascade finishes the backport which paused the cascade once its
+// conflicts are resolved and committed, and backports onto the rest of the
+// targets.
+func ContinueCascade(ctx context.Context) error {
+	cascade, err := loadCascade(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading the cascade: %w", err)
+	} else if cascade == nil {
+		return fmt.Errorf("no cascade in progress")
+	}
+
+	cherryPick := cascade.CherryPick
+	cherryPick.OnTo = cascade.Targets[0]
+	err = tui.WithStep(ctx, "checking the conflicts are resolved", func(ctx context.Context, logger log.Logger) error {
+		if inCherryPick, err := IsInCherryPick(ctx); err != nil {
+			return fmt.Errorf("error checking if the repository is in a cherry-pick: %w", err)
+		} else if inCherryPick {
+			return fmt.Errorf("the cherry-pick is in progress. run %s after resolving the conflicts", color.Green("`git cherry-pick --continue`"))
+		}
+		if inAm, err := IsInRebaseOrAm(ctx); err != nil {
+			return fmt.Errorf("error checking if the repository is in an am: %w", err)
+		} else if inAm {
+			return fmt.Errorf("the am is in progress. run %s after resolving the conflicts", color.Green("`git am --continue`"))
+		}
+
+		if branch, err := GetCurrentBranch(ctx); err != nil {
+			return fmt.Errorf("error getting the current branch: %w", err)
+		} else if branch != cascade.Branch {
+			return fmt.Errorf("the backport branch %s is not checked out (on %s)", cascade.Branch, branch)
+		}
+
+		logger.Successf("resolved the backport onto %s", color.Cyan(cherryPick.OnTo))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = cherryPick.resume(ctx, cascade.Branch, cascade.Previous); err != nil {
+		return err
+	}
+	cascade.next(&cherryPick)
+	if err = removeCascade(ctx); err != nil {
+		return err
+	}
+	return cascade.RunWithContext(ctx)
+}
+
+// resume finishes the backport onto OnTo, whose conflicts were resolved on
+// branch, as RunWithContext would have. from is the previous target of the
+// cascade, if any.
+func (cherryPick *CherryPick) resume(ctx context.Context, branch, from string) error {
+	currentRepo, err := GetRepository(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting the current repository: %w", err)
+	}
+	repo := currentRepo
+	if cherryPick.Repo != nil {
+		repo = *cherryPick.Repo
+		if repo.Host == "" {
+			repo.Host = currentRepo.Host
+		}
+	}
+
+	pr, err := GetPullRequest(ctx, repo, cherryPick.PRNumber)
+	if err != nil {
+		return fmt.Errorf("error getting the pull request: %w", err)
+	}
+	cherryPick.repo, cherryPick.pr = repo, pr
+
+	desc := description{PR: pr, PRRef: fmt.Sprintf("#%d", pr.Number), OnTo: cherryPick.OnTo, ForwardPort: cherryPick.ForwardPort}
+	if !repo.Equal(currentRepo) {
+		desc.PRRef = fmt.Sprintf("%s#%d", repo.NameWithOwner(), pr.Number)
+	}
+	if from != "" {
+		desc.Notes = append(desc.Notes, cascadeNote(from))
+	}
+	cherryPick.desc = &desc
+	cherryPick.Result.Branch = branch
+
+	if cherryPick.state, err = LoadState(ctx); err != nil {
+		return fmt.Errorf("error loading the state: %w", err)
+	}
+	return cherryPick.finish(ctx, branch, desc)
+}
+
+// runCascaded cherry-picks the commits of the backport onto the previous
+// target of a cascade, with its conflict resolutions, instead of the PR.
+func (cherryPick *CherryPick) runCascaded(ctx context.Context, desc description) error {
+	logger := log.LoggerFromCtx(ctx)
+
+	var branchName = fmt.Sprintf("%s-pr-%d-onto-%s-%d", desc.kind(), cherryPick.PRNumber, strings.ReplaceAll(cherryPick.OnTo, "/", "-"), time.Now().Unix())
+	err := tui.WithStep(ctx, "checking out branch", func(ctx context.Context, logger log.Logger) error {
+		return cherryPick.checkoutBranch(ctx, logger, branchName, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	desc.Notes = append(desc.Notes, cascadeNote(cherryPick.cascadeFrom))
+	err = tui.WithStep(ctx, "cherry-picking the backport onto "+cherryPick.cascadeFrom, func(ctx context.Context, logger log.Logger) error {
+		logger.WithField("commits", len(cherryPick.cascadeCommits)).Infof("cherry-picking")
+		if err := cherryPick.cherryPickCommits(ctx, cherryPick.cascadeCommits...); err != nil {
+			return fmt.Errorf("error cherry-picking the backport onto %s\n%w", cherryPick.cascadeFrom, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Successf("cherry-picked branch %s onto %s", color.Cyan(branchName), color.Cyan(cherryPick.OnTo))
+
+	return cherryPick.finish(ctx, branchName, desc)
+}
+
+// cascadeNote tells which backport the changes come from.
+func cascadeNote(from string) string {
+	return fmt.Sprintf("Cascaded from the backport onto `%s`, with its conflict resolutions.", from)
+}
+
+func loadCascade(ctx context.Context) (*Cascade, error) {
+	path, err := cascadePath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var cascade Cascade
+	if err = json.Unmarshal(data, &cascade); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", path, err)
+	}
+	if len(cascade.Targets) == 0 {
+		return nil, fmt.Errorf("%s has no targets", path)
+	}
+	return &cascade, nil
+}
+
+func (cascade *Cascade) save(ctx context.Context) error {
+	path, err := cascadePath(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cascade, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the cascade: %w", err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func removeCascade(ctx context.Context) error {
+	path, err := cascadePath(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+func cascadePath(ctx context.Context) (string, error) {
+	gitDir, err := GetGitDir(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the git directory: %w", err)
+	}
+	return filepath.Join(gitDir, cascadeStateFileName), nil
+}
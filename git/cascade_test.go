@@ -0,0 +1,34 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewCascade(t *testing.T) {
+	testcases := []struct {
+		name     string
+		targets  []string
+		expected []string
+	}{{
+		name:     "newest first",
+		targets:  []string{"release/1.1", "release/1.3", "release/1.2"},
+		expected: []string{"release/1.3", "release/1.2", "release/1.1"},
+	}, {
+		name:     "minor versions compared as numbers",
+		targets:  []string{"release/1.9", "release/1.10"},
+		expected: []string{"release/1.10", "release/1.9"},
+	}, {
+		name:     "branches without a version first",
+		targets:  []string{"release/2.0", "main"},
+		expected: []string{"main", "release/2.0"},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := NewCascade(CherryPick{}, tc.targets).Targets; !slices.Equal(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
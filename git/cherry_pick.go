@@ -5,8 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,61 +19,173 @@ import (
 )
 
 type CherryPick struct {
-	PRNumber      int
-	OnTo          string
+	PRNumber int
+	// Repo is the repository PRNumber and CommitSHA belong to. It defaults to the current repository.
+	Repo *gitobj.Repository
+	// CommitSHA is a merge commit which is resolved to its PR when PRNumber is not set.
+	CommitSHA string
+	// CommitRange is cherry-picked as is when the change has no PR.
+	CommitRange string
+	OnTo        string
+	// StartPoint is a tag, branch or commit which OnTo is created from on
+	// origin when it does not exist, e.g. to hotfix a release without a
+	// release branch.
+	StartPoint    string
 	MergeStrategy MergeStrategy
 	Push          bool
 	Worktree      bool
+	// AllowUnmerged cherry-picks the commits of an open or closed PR instead of its merge commit.
+	AllowUnmerged bool
+	// Commits selects which commits of the PR or the commit range are cherry-picked.
+	Commits CommitSelection
+	// Paths restricts which files are cherry-picked.
+	Paths PathFilter
+	// AutoStash stashes local changes before checking out the cherry-pick
+	// branch, and restores them on the original branch after success.
+	AutoStash bool
+	// KeepOnFailure keeps the cherry-pick branch checked out when cherry-picking
+	// fails or is interrupted. Conflicts always keep it for manual resolution.
+	KeepOnFailure bool
+	// CreatePR pushes the cherry-pick branch and opens a pull request for it.
+	CreatePR bool
+	// Metadata selects what the pull request created with CreatePR gets from the PR.
+	Metadata MetadataOptions
+	// Resolvers resolve conflicts automatically, before those of the
+	// gh-cherry-pick.resolve git config.
+	Resolvers Resolvers
+	// IncludePrerequisites cherry-picks the missing PRs which the conflicting
+	// lines were changed by first, oldest first, when cherry-picking a PR
+	// conflicts, and retries on top of them.
+	IncludePrerequisites bool
+	// IncludeFollowUps cherry-picks the later commits of the base branch which
+	// refer to the merged PR, e.g. fixes of it, on top of it.
+	IncludeFollowUps bool
+	// OnConflict is what to do when cherry-picking a PR stops on conflicts.
+	OnConflict ConflictAction
+	// ForwardPort cherry-picks a PR of a release branch onto a newer line,
+	// e.g. the default branch, which it verifies OnTo is.
+	ForwardPort bool
+	// Revert reverts the backport of the merged PR on OnTo instead of
+	// cherry-picking it.
+	Revert bool
+	// RecordOrigin appends the "(cherry picked from commit <sha>)" trailer of
+	// git cherry-pick -x to the cherry-picked commits, which finds the
+	// backports to revert. Without it, they are found by their patch ID.
+	RecordOrigin bool
+	// Comment lists the outcome in a comment on the PR, which is updated by
+	// the cherry-picks onto other targets.
+	Comment bool
+
+	// Result is filled in while running.
+	Result Result
+
+	state     *State
+	resolvers Resolvers
+	// includingPrerequisites is set once the prerequisites are included, so
+	// that they are included only once.
+	includingPrerequisites bool
+	// followUps are included on top of the PR with IncludeFollowUps.
+	followUps []FollowUp
+	// cascadeCommits are cherry-picked instead of the PR: those of its
+	// backport onto cascadeFrom, the previous target of a Cascade.
+	cascadeFrom    string
+	cascadeCommits []string
+	// startCommit is the commit of StartPoint, when OnTo is to be created
+	// from it.
+	startCommit string
+	// repo and pr are the cherry-picked PR once it is resolved, and desc describes it.
+	repo gitobj.Repository
+	pr   *gitobj.PullRequest
+	desc *description
 }
 
-func (cherryPick *CherryPick) RunWithContext(ctx context.Context) error {
-	logger := log.LoggerFromCtx(ctx)
+type Result struct {
+	// Branch is the cherry-pick branch.
+	Branch string
+	// PullRequestURL is the URL of the pull request created with CreatePR.
+	PullRequestURL string
+	// AlreadyPresent is set when the target already has the changes, in which
+	// case nothing is pushed.
+	AlreadyPresent bool
+	// Draft is set when the conflicts are pushed as a draft pull request, with
+	// the conflicting files in ConflictedFiles.
+	Draft           bool
+	ConflictedFiles []string
+	// Prerequisites are the missing PRs which the conflicting lines were
+	// changed by, which are included with IncludePrerequisites.
+	Prerequisites []Prerequisite
+	// Commits are the commits of the pushed cherry-pick branch, oldest first.
+	Commits []string
+	// Dir is the repository the cherry-pick ran in, which is the worktree
+	// cache with Worktree.
+	Dir string
+}
 
-	logger.Infof("🍒 %s", color.Bold("starting cherry-picker\n"))
+func (cherryPick *CherryPick) RunWithContext(ctx context.Context) (err error) {
+	logger := log.LoggerFromCtx(ctx)
 
-	if cherryPick.Worktree {
-		err := tui.WithStep(ctx, "setting up worktree cache", func(ctx context.Context, logger log.Logger) error {
-			nameWithOwner, err := GetNameWithOwner(ctx)
-			if err != nil {
-				return fmt.Errorf("error getting repository name: %w", err)
+	defer func() {
+		var conflictErr *ConflictError
+		if ctx.Err() == nil && cherryPick.OnConflict == ConflictActionDraftPR && cherryPick.desc != nil && errors.As(err, &conflictErr) {
+			if draftErr := cherryPick.pushConflicts(ctx); draftErr != nil {
+				err = fmt.Errorf("%w\n\nerror pushing the conflicts as a draft pull request: %w", err, draftErr)
+			} else {
+				err = nil
 			}
+		}
 
-			remoteURL, err := GetRemoteURL(ctx)
-			if err != nil {
-				return fmt.Errorf("error getting remote URL: %w", err)
-			}
+		if cherryPick.Comment && cherryPick.pr != nil {
+			cherryPick.comment(context.WithoutCancel(ctx), err)
+		}
 
-			parts := strings.SplitN(nameWithOwner, "/", 2)
-			ownerDir := filepath.Join(os.TempDir(), "gh-cherry-pick", parts[0])
-			cacheDir := filepath.Join(ownerDir, parts[1])
+		if err == nil || cherryPick.state == nil {
+			return
+		}
 
-			if err := os.MkdirAll(ownerDir, 0755); err != nil {
-				return fmt.Errorf("error creating cache directory: %w", err)
+		state := cherryPick.state
+		if (ctx.Err() != nil || !errors.As(err, &conflictErr)) && !cherryPick.KeepOnFailure {
+			// ctx may be cancelled by an interrupt, but cleaning up must still run.
+			cleanupErr := tui.WithStep(context.WithoutCancel(ctx), "cleaning up", func(ctx context.Context, logger log.Logger) error {
+				return restoreState(ctx, logger, state, false)
+			})
+			if cleanupErr == nil {
+				cherryPick.state = nil
+				return
 			}
+			err = fmt.Errorf("%w\n\nerror cleaning up: %w", err, cleanupErr)
+		}
 
-			if _, statErr := os.Stat(filepath.Join(cacheDir, ".git")); os.IsNotExist(statErr) {
-				logger.Infof("cloning repository to cache: %s", cacheDir)
-				if err := Clone(ctx, remoteURL, cacheDir); err != nil {
-					return fmt.Errorf("error cloning repository: %w", err)
-				}
-			} else {
-				logger.Infof("using cached repository: %s", cacheDir)
-			}
+		if state.StashRef != "" {
+			err = fmt.Errorf("%w\n\nyour local changes are stashed in %s\nrun %s to go back to %s and restore them\nrun %s on %s to restore them after finishing the cherry-pick yourself",
+				err, color.Cyan(state.StashRef[:7]), color.Yellow("`gh cherry-pick abort`"), color.Cyan(state.OriginalRef()),
+				color.Green("`git stash pop`"), color.Cyan(state.OriginalRef()))
+		} else {
+			err = fmt.Errorf("%w\n\nrun %s to go back to %s and delete %s",
+				err, color.Yellow("`gh cherry-pick abort`"), color.Cyan(state.OriginalRef()), color.Cyan(state.Branch))
+		}
+	}()
 
-			return os.Chdir(cacheDir)
-		})
+	logger.Infof("🍒 %s", color.Bold("starting cherry-picker\n"))
+
+	if cherryPick.Worktree {
+		cacheDir, err := worktreeCache(ctx)
 		if err != nil {
 			return err
 		}
+
+		ctx = CtxWithDir(ctx, cacheDir)
 	}
+	cherryPick.Result.Dir = DirFromCtx(ctx)
 
-	err := tui.WithStep(ctx, "checking is repository ready", func(ctx context.Context, logger log.Logger) error {
+	err = tui.WithStep(ctx, "checking is repository ready", func(ctx context.Context, logger log.Logger) error {
 		if !cherryPick.Worktree {
 			logger.Infof("checking is repository dirty")
 			if dirty, err := IsDirty(ctx); err != nil {
 				return fmt.Errorf("error checking if the repository is dirty: %w", err)
+			} else if dirty && cherryPick.AutoStash {
+				logger.Infof("the repository is dirty. local changes will be stashed")
 			} else if dirty {
-				return fmt.Errorf("the repository is dirty. please commit your changes before continuing")
+				return fmt.Errorf("the repository is dirty. please commit your changes before continuing, or use %s", color.Yellow("-autostash"))
 			}
 
 			logger.Infof("checking is repository in a rebase or am")
@@ -81,6 +194,51 @@ func (cherryPick *CherryPick) RunWithContext(ctx context.Context) error {
 			} else if rebaseOrAm {
 				return fmt.Errorf("the repository is in a rebase or am. please resolve the rebase or am before continuing")
 			}
+
+			if state, err := LoadState(ctx); err != nil {
+				return fmt.Errorf("error loading the state: %w", err)
+			} else if state != nil {
+				logger.WithField("branch", state.Branch).Warnf("discarding the state of a previous cherry-pick which was not aborted")
+				if state.StashRef != "" {
+					logger.Warnf("its stashed changes are kept in the stash list as %s", color.Cyan(state.StashRef[:7]))
+				}
+				if err = RemoveState(ctx); err != nil {
+					return fmt.Errorf("error removing the state: %w", err)
+				}
+			}
+		}
+
+		if cherryPick.StartPoint != "" {
+			return cherryPick.prepareTarget(ctx, logger)
+		}
+		logger.WithField("branch", cherryPick.OnTo).Infof("checking the target branch exists")
+		return checkTargetExists(ctx, cherryPick.OnTo)
+	})
+	if err != nil {
+		return err
+	}
+
+	var repo gitobj.Repository
+	var sourceRemote = "origin"
+	err = tui.WithStep(ctx, "resolving the repository", func(ctx context.Context, logger log.Logger) error {
+		currentRepo, err := GetRepository(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting the current repository: %w", err)
+		}
+
+		repo = currentRepo
+		if cherryPick.Repo != nil {
+			repo = *cherryPick.Repo
+			if repo.Host == "" {
+				repo.Host = currentRepo.Host
+			}
+		}
+
+		if !repo.Equal(currentRepo) {
+			sourceRemote = repo.CloneURL()
+			logger.WithField("remote", sourceRemote).Infof("using %s as the source repository", color.Cyan(repo.NameWithOwner()))
+		} else {
+			logger.Infof("using %s as the source repository", color.Cyan(repo.NameWithOwner()))
 		}
 
 		return nil
@@ -89,17 +247,40 @@ func (cherryPick *CherryPick) RunWithContext(ctx context.Context) error {
 		return err
 	}
 
+	if cherryPick.CommitRange != "" {
+		return cherryPick.runCommitRange(ctx)
+	}
+
 	var pr *gitobj.PullRequest
 	err = tui.WithStep(ctx, "validating the pull request", func(ctx context.Context, logger log.Logger) error {
+		if cherryPick.PRNumber == 0 && cherryPick.CommitSHA != "" {
+			logger.WithField("commit", cherryPick.CommitSHA).Infof("resolving the pull request of the commit")
+			if cherryPick.PRNumber, err = GetPullRequestNumberForCommit(ctx, repo, cherryPick.CommitSHA); err != nil {
+				return fmt.Errorf("error resolving the pull request of commit %s: %w", cherryPick.CommitSHA, err)
+			} else if cherryPick.PRNumber == 0 {
+				return fmt.Errorf("no merged pull request found for commit %s. use a commit range (%s^..%s) to cherry-pick it without a PR", cherryPick.CommitSHA, cherryPick.CommitSHA, cherryPick.CommitSHA)
+			}
+		}
+
 		logger.WithField("pr", cherryPick.PRNumber).Infof("fetching the pull request")
-		if pr, err = GetPullRequest(ctx, cherryPick.PRNumber); err != nil {
+		if pr, err = GetPullRequest(ctx, repo, cherryPick.PRNumber); err != nil {
 			return fmt.Errorf("error getting the pull request: %w", err)
 		}
 
 		logger.Successf("%s  %s %s", pr.PRNumberString(), pr.Url, color.Grey(pr.Author.Login))
 
+		if sourceRemote == "origin" && cherryPick.OnTo == pr.BaseRefName {
+			return fmt.Errorf("%s is the base branch of the PR, which has its changes already. choose another target branch", cherryPick.OnTo)
+		}
+
 		if pr.State != gitobj.PullRequestStateMerged {
-			return fmt.Errorf("PR is not merged (current state: %s). please ensure the PR is merged before continuing", pr.StateString())
+			if cherryPick.Revert {
+				return fmt.Errorf("PR is not merged (current state: %s). only the backports of a merged PR can be reverted", pr.StateString())
+			}
+			if !cherryPick.AllowUnmerged {
+				return fmt.Errorf("PR is not merged (current state: %s). please ensure the PR is merged before continuing, or use %s", pr.StateString(), color.Yellow("-allow-unmerged"))
+			}
+			logger.Warnf("PR is not merged (current state: %s). its own commits will be cherry-picked", pr.StateString())
 		}
 
 		return nil
@@ -108,12 +289,41 @@ func (cherryPick *CherryPick) RunWithContext(ctx context.Context) error {
 		return err
 	}
 
-	var mergeStrategy MergeStrategy
+	cherryPick.repo, cherryPick.pr = repo, pr
+	merged := pr.State == gitobj.PullRequestStateMerged
+	desc := description{PR: pr, PRRef: fmt.Sprintf("#%d", pr.Number), OnTo: cherryPick.OnTo}
+	if sourceRemote != "origin" {
+		desc.PRRef = fmt.Sprintf("%s#%d", repo.NameWithOwner(), pr.Number)
+	}
+	cherryPick.desc = &desc
+	if cherryPick.Revert {
+		return cherryPick.runRevert(ctx, sourceRemote, pr, desc)
+	}
+	if cherryPick.ForwardPort {
+		desc.ForwardPort = true
+		if err = cherryPick.checkNewerLine(ctx, repo, pr); err != nil {
+			return err
+		}
+	}
+	if len(cherryPick.cascadeCommits) > 0 {
+		return cherryPick.runCascaded(ctx, desc)
+	}
+	if !merged {
+		desc.Warnings = append(desc.Warnings, fmt.Sprintf("%s was **not merged** (state: `%s`) when it was cherry-picked. The cherry-pick contains its commits at that time, which may differ from what is eventually merged.", desc.PRRef, strings.ToLower(string(pr.State))))
+	}
+
+	var mergeStrategy = cherryPick.MergeStrategy
 	err = tui.WithStep(ctx, "determining merge strategy", func(ctx context.Context, logger log.Logger) error {
-		if cherryPick.MergeStrategy == MergeStrategyAuto {
+		if len(cherryPick.Commits) > 0 {
+			mergeStrategy = MergeStrategyRebase
+			logger.Infof("commits are selected, use merge strategy %s to cherry-pick them", color.Cyan(mergeStrategy))
+		} else if cherryPick.MergeStrategy == MergeStrategyAuto && !merged {
+			mergeStrategy = MergeStrategyRebase
+			logger.Infof("PR is not merged, use merge strategy %s to cherry-pick its commits", color.Cyan(mergeStrategy))
+		} else if cherryPick.MergeStrategy == MergeStrategyAuto {
 			logger.Infof("no merge strategy given, determining merge strategy")
 
-			if mergeStrategy, err = PRMergedWith(ctx, cherryPick.PRNumber); err != nil {
+			if mergeStrategy, err = PRMergedWith(ctx, repo, cherryPick.PRNumber); err != nil {
 				return fmt.Errorf("error determining merge strategy: %w", err)
 			}
 
@@ -128,51 +338,124 @@ func (cherryPick *CherryPick) RunWithContext(ctx context.Context) error {
 		return err
 	}
 
-	var cherryPickBranchName = fmt.Sprintf("cherry-pick-pr-%d-onto-%s-%d", cherryPick.PRNumber, strings.ReplaceAll(cherryPick.OnTo, "/", "-"), time.Now().Unix())
+	var cherryPickBranchName = fmt.Sprintf("%s-pr-%d-onto-%s-%d", desc.kind(), cherryPick.PRNumber, strings.ReplaceAll(cherryPick.OnTo, "/", "-"), time.Now().Unix())
+	// baseCommit is the head of the base branch of the PR, which may be of
+	// another repository than origin.
+	var baseCommit string
 	err = tui.WithStep(ctx, "checking out branch", func(ctx context.Context, logger log.Logger) error {
 		logger.WithField("branch", pr.BaseRefName).Infof("fetching the branch")
-		if err = Fetch(ctx, "origin", pr.BaseRefName); err != nil {
-			return fmt.Errorf("error fetching the branch '%s': %w", cherryPick.OnTo, err)
+		if err = Fetch(ctx, sourceRemote, pr.BaseRefName); err != nil {
+			return fmt.Errorf("error fetching the branch '%s': %w", pr.BaseRefName, err)
 		}
-
-		if cherryPick.OnTo != pr.BaseRefName {
-			logger.WithField("branch", cherryPick.OnTo).Infof("fetching the branch")
-			if err = Fetch(ctx, "origin", cherryPick.OnTo); err != nil {
-				return fmt.Errorf("error fetching the branch '%s': %w", cherryPick.OnTo, err)
-			}
+		if baseCommit, err = RevParse(ctx, "FETCH_HEAD"); err != nil {
+			return fmt.Errorf("error resolving the branch '%s': %w", pr.BaseRefName, err)
 		}
 
-		logger.WithField("branch", cherryPickBranchName).
-			WithField("base", cherryPick.OnTo).
-			Infof("checking out to new branch")
-		if err = CheckoutNewBranch(ctx, cherryPickBranchName, "origin", cherryPick.OnTo); err != nil {
-			return fmt.Errorf("error checking out to new branch '%s': %w", cherryPickBranchName, err)
+		if merged {
+			if err = cherryPick.checkNotContained(ctx, logger, pr); err != nil {
+				return err
+			}
 		}
-
-		return nil
+		return cherryPick.checkoutBranch(ctx, logger, cherryPickBranchName, merged)
 	})
 	if err != nil {
 		return err
 	}
 
+	if merged {
+		if err = cherryPick.checkFollowUps(ctx, baseCommit, mergeStrategy, &desc); err != nil {
+			return err
+		}
+	}
+
+	if partial := len(cherryPick.Commits) > 0 || !cherryPick.Paths.IsEmpty(); partial {
+		note := partialNote(desc.kind(), desc.PRRef, cherryPick.Commits, cherryPick.Paths)
+		desc.Notes = append(desc.Notes, note)
+
+		err = tui.WithStep(ctx, "cherry-picking part of PR", func(ctx context.Context, logger log.Logger) error {
+			var commits []string
+			if len(cherryPick.Commits) > 0 || !merged || mergeStrategy == MergeStrategyRebase {
+				prCommits, err := cherryPick.fetchPRCommits(ctx, logger, sourceRemote, pr)
+				if err != nil {
+					return err
+				}
+				for _, commit := range prCommits {
+					commits = append(commits, commit.Oid)
+				}
+			} else {
+				commits = []string{pr.MergeCommit.Sha}
+			}
+
+			return cherryPick.applyPartial(ctx, logger, commits, note)
+		})
+		if err != nil {
+			return err
+		}
+		logger.Successf("cherry-picked part of PR onto branch %s", color.Cyan(cherryPickBranchName))
+
+		if err = cherryPick.applyFollowUps(ctx); err != nil {
+			return err
+		}
+		return cherryPick.finish(ctx, cherryPickBranchName, desc)
+	}
+
+	if !merged {
+		err = tui.WithStep(ctx, "cherry-picking unmerged PR", func(ctx context.Context, logger log.Logger) error {
+			prCommits, err := cherryPick.fetchPRCommits(ctx, logger, sourceRemote, pr)
+			if err != nil {
+				return err
+			}
+			commits := make([]string, 0, len(prCommits))
+			for _, commit := range prCommits {
+				commits = append(commits, commit.Oid)
+			}
+
+			if mergeStrategy == MergeStrategySquash {
+				// The PR may have merged its base branch in, whose changes are
+				// not part of it, so the diff starts where it branched off.
+				head, err := RevParse(ctx, "FETCH_HEAD")
+				if err != nil {
+					return fmt.Errorf("error resolving the PR head: %w", err)
+				}
+				base, err := MergeBase(ctx, baseCommit, head)
+				if err != nil {
+					return fmt.Errorf("error finding where the PR branched off %s: %w", pr.BaseRefName, err)
+				}
+
+				logger.WithField("commits", len(commits)).WithField("base", base[:7]).Infof("applying the squashed diff")
+				message := fmt.Sprintf("%s (%s)", pr.Title, desc.PRRef)
+				if err = cherryPick.applySquashed(ctx, base, head, message); err != nil {
+					return fmt.Errorf("error applying the squashed PR diff\n%w", err)
+				}
+				return nil
+			}
+
+			logger.WithField("commits", len(commits)).Infof("cherry-picking")
+			if err = cherryPick.cherryPickCommits(ctx, commits...); err != nil {
+				return fmt.Errorf("error cherry-picking PR commits\n%w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		logger.Successf("cherry-picked branch %s onto %s", color.Cyan(cherryPickBranchName), color.Cyan(cherryPick.OnTo))
+
+		return cherryPick.finish(ctx, cherryPickBranchName, desc)
+	}
+
 	switch mergeStrategy {
 	case MergeStrategyRebase:
 		err = tui.WithStep(ctx, "rebasing PR", func(ctx context.Context, logger log.Logger) error {
 			logger.WithField("pr", cherryPick.PRNumber).Infof("fetching diff")
 			var prDiff bytes.Buffer
-			if err = NewCommand("gh", "pr", "diff", strconv.Itoa(cherryPick.PRNumber), "--patch").Run(ctx, WithStdout(&prDiff)); err != nil {
+			if err = NewCommand("gh", "pr", "diff", strconv.Itoa(cherryPick.PRNumber), "--repo", repo.String(), "--patch").Run(ctx, WithStdout(&prDiff)); err != nil {
 				return fmt.Errorf("error getting PR diff: %w", err)
 			}
 
 			logger.Infof("applying diff")
-			if err = NewCommand("git", "am", "-3").Run(ctx, WithStdin(&prDiff)); err != nil {
-				helpMsg := fmt.Sprintf("run %s after resolve the conflicts\nrun %s if you want to abort the rebase", color.Green("`git am --continue`"), color.Yellow("`git am --abort`"))
-
-				var gitError *GitError
-				if errors.As(err, &gitError) && gitError.ExitCode == 1 && strings.Contains(gitError.Stderr, "error: Failed to merge in the changes") {
-					return fmt.Errorf("error applying PR diff\n%s", helpMsg)
-				}
-				return fmt.Errorf("error applying PR diff\n%s\n\n%w", helpMsg, err)
+			if err = cherryPick.applyPatches(ctx, &prDiff); err != nil {
+				return fmt.Errorf("error applying PR diff\n%w", err)
 			}
 
 			return nil
@@ -185,14 +468,8 @@ func (cherryPick *CherryPick) RunWithContext(ctx context.Context) error {
 	case MergeStrategySquash:
 		err = tui.WithStep(ctx, "cherry-picking PR merge commit", func(ctx context.Context, logger log.Logger) error {
 			logger.WithField("merge_commit", pr.MergeCommit.Sha[:7]).Infof("cherry-picking")
-			if err = NewCommand("git", "cherry-pick", "--keep-redundant-commits", pr.MergeCommit.Sha).Run(ctx); err != nil {
-				helpMsg := fmt.Sprintf("run %v after resolve the conflicts\nrun %v if you want to abort the cherry-pick", color.Green("`git cherry-pick --continue`"), color.Yellow("`git cherry-pick --abort`"))
-
-				var gitError *GitError
-				if errors.As(err, &gitError) && gitError.ExitCode == 1 && strings.Contains(gitError.Stderr, "error: could not apply") {
-					return fmt.Errorf("error cherry-picking PR merge commit\n%s", helpMsg)
-				}
-				return fmt.Errorf("error cherry-picking PR merge commit\n%s\n\n%w", helpMsg, err)
+			if err = cherryPick.cherryPickCommits(ctx, pr.MergeCommit.Sha); err != nil {
+				return fmt.Errorf("error cherry-picking PR merge commit\n%w", err)
 			}
 
 			return nil
@@ -203,29 +480,471 @@ func (cherryPick *CherryPick) RunWithContext(ctx context.Context) error {
 		logger.Successf("cherry-picked branch %s onto %s", color.Cyan(cherryPickBranchName), color.Cyan(cherryPick.OnTo))
 	}
 
-	if cherryPick.Push || cherryPick.Worktree {
-		err = tui.WithStep(ctx, "pushing branch", func(ctx context.Context, logger log.Logger) error {
-			logger.WithField("branch", cherryPickBranchName).Infof("pushing")
-			if err = Push(ctx, "origin", cherryPickBranchName); err != nil {
-				return fmt.Errorf("error pushing branch %s: %w", cherryPickBranchName, err)
+	if err = cherryPick.applyFollowUps(ctx); err != nil {
+		return err
+	}
+	return cherryPick.finish(ctx, cherryPickBranchName, desc)
+}
+
+// runCommitRange cherry-picks a bare commit range which has no PR.
+func (cherryPick *CherryPick) runCommitRange(ctx context.Context) error {
+	logger := log.LoggerFromCtx(ctx)
+
+	var commits []string
+	err := tui.WithStep(ctx, "validating the commit range", func(ctx context.Context, logger log.Logger) error {
+		var err error
+		logger.WithField("range", cherryPick.CommitRange).Infof("listing commits")
+		if commits, err = RevList(ctx, cherryPick.CommitRange); err != nil {
+			return fmt.Errorf("error listing commits of %s. make sure both ends are fetched: %w", cherryPick.CommitRange, err)
+		} else if len(commits) == 0 {
+			return fmt.Errorf("commit range %s is empty", cherryPick.CommitRange)
+		}
+
+		logger.Successf("found %d commit(s) in %s", len(commits), color.Cyan(cherryPick.CommitRange))
+
+		if len(cherryPick.Commits) > 0 {
+			rangeCommits := make([]gitobj.Commit, 0, len(commits))
+			for _, commit := range commits {
+				rangeCommits = append(rangeCommits, gitobj.Commit{Oid: commit})
+			}
+			selected, err := cherryPick.Commits.Select(rangeCommits)
+			if err != nil {
+				return fmt.Errorf("error selecting commits: %w", err)
 			}
 
-			repoWebURL, repoURLErr := GetRepoWebURL(ctx)
-			if repoURLErr == nil {
-				logger.Successf("pushed branch %s\ncreate a pull request by visiting:\n    %s",
-					color.Cyan(cherryPickBranchName),
-					fmt.Sprintf("%s/compare/%s...%s", repoWebURL, cherryPick.OnTo, cherryPickBranchName),
-				)
-			} else {
-				logger.Successf("pushed branch %s", color.Cyan(cherryPickBranchName))
+			commits = commits[:0]
+			for _, commit := range selected {
+				commits = append(commits, commit.Oid)
 			}
+			logger.Infof("selected %d commit(s)", len(commits))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-			return nil
-		})
+	rangeName := fmt.Sprintf("%.7s-%.7s", commits[0], commits[len(commits)-1])
+	var cherryPickBranchName = fmt.Sprintf("cherry-pick-%s-onto-%s-%d", rangeName, strings.ReplaceAll(cherryPick.OnTo, "/", "-"), time.Now().Unix())
+	err = tui.WithStep(ctx, "checking out branch", func(ctx context.Context, logger log.Logger) error {
+		return cherryPick.checkoutBranch(ctx, logger, cherryPickBranchName, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	desc := description{CommitRange: cherryPick.CommitRange, OnTo: cherryPick.OnTo}
+	// OnConflict pushes the conflicts with it as a draft pull request.
+	cherryPick.desc = &desc
+	err = tui.WithStep(ctx, "cherry-picking commits", func(ctx context.Context, logger log.Logger) error {
+		if len(cherryPick.Commits) > 0 || !cherryPick.Paths.IsEmpty() {
+			note := partialNote(desc.kind(), cherryPick.CommitRange, cherryPick.Commits, cherryPick.Paths)
+			desc.Notes = append(desc.Notes, note)
+			return cherryPick.applyPartial(ctx, logger, commits, note)
+		}
+
+		logger.WithField("range", cherryPick.CommitRange).Infof("cherry-picking")
+		if err = cherryPick.cherryPickCommits(ctx, commits...); err != nil {
+			return fmt.Errorf("error cherry-picking commits\n%w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Successf("cherry-picked branch %s onto %s", color.Cyan(cherryPickBranchName), color.Cyan(cherryPick.OnTo))
+
+	return cherryPick.finish(ctx, cherryPickBranchName, desc)
+}
+
+// fetchPRCommits fetches the head of the PR and returns its commits, limited
+// to the selected ones when Commits is set. Merge commits, e.g. of the base
+// branch into the PR, are skipped, since they cannot be cherry-picked.
+func (cherryPick *CherryPick) fetchPRCommits(ctx context.Context, logger log.Logger, remote string, pr *gitobj.PullRequest) ([]gitobj.Commit, error) {
+	headRef := fmt.Sprintf("refs/pull/%d/head", pr.Number)
+	logger.WithField("ref", headRef).Infof("fetching the PR head")
+	if err := Fetch(ctx, remote, headRef); err != nil {
+		return nil, fmt.Errorf("error fetching '%s': %w", headRef, err)
+	}
+
+	if len(pr.Commits) == 0 {
+		return nil, fmt.Errorf("PR %s has no commits", pr.PRNumberString())
+	}
+	commits := pr.Commits
+	if len(cherryPick.Commits) > 0 {
+		var err error
+		if commits, err = cherryPick.Commits.Select(pr.Commits); err != nil {
+			return nil, fmt.Errorf("error selecting commits: %w", err)
+		}
+		for _, commit := range commits {
+			logger.WithField("commit", commit.Oid[:7]).Infof("selected %s", commit.MessageHeadline)
+		}
+	}
+	return skipMergeCommits(ctx, logger, commits)
+}
+
+// skipMergeCommits returns commits without the merge commits.
+func skipMergeCommits(ctx context.Context, logger log.Logger, commits []gitobj.Commit) ([]gitobj.Commit, error) {
+	oids := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		oids = append(oids, commit.Oid)
+	}
+	merges, err := MergeCommits(ctx, oids...)
+	if err != nil {
+		return nil, fmt.Errorf("error finding the merge commits: %w", err)
+	}
+
+	kept := commits[:0:0]
+	for _, commit := range commits {
+		if slices.Contains(merges, commit.Oid) {
+			logger.WithField("commit", commit.Oid[:7]).Warnf("skipping merge commit %s", commit.MessageHeadline)
+			continue
+		}
+		kept = append(kept, commit)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("nothing to cherry-pick: the commits are all merge commits")
+	}
+	return kept, nil
+}
+
+// applyPartial applies the given commits limited to Paths, with note
+// appended to each commit message.
+func (cherryPick *CherryPick) applyPartial(ctx context.Context, logger log.Logger, commits []string, note string) error {
+	logger.WithField("commits", len(commits)).Infof("formatting patches")
+	patches, err := formatPatches(ctx, commits, cherryPick.Paths, note)
+	if err != nil {
+		return err
+	}
+	if patches.Len() == 0 {
+		return fmt.Errorf("nothing to cherry-pick: the selected commits have no changes in the selected paths")
+	}
+
+	logger.Infof("applying patches")
+	if err = cherryPick.applyPatches(ctx, patches); err != nil {
+		return fmt.Errorf("error applying patches\n%w", err)
+	}
+	return nil
+}
+
+// worktreeCache returns the clone of the current repository cached in the OS
+// temp directory, which Worktree runs in.
+func worktreeCache(ctx context.Context) (string, error) {
+	var cacheDir string
+	err := tui.WithStep(ctx, "setting up worktree cache", func(ctx context.Context, logger log.Logger) error {
+		repo, err := GetRepository(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting repository name: %w", err)
+		}
+
+		remoteURL, err := GetRemoteURL(ctx)
 		if err != nil {
+			return fmt.Errorf("error getting remote URL: %w", err)
+		}
+
+		cacheDir, err = PrepareCache(ctx, logger, repo, remoteURL)
+		return err
+	})
+	return cacheDir, err
+}
+
+// checkoutBranch fetches the target branch and creates the cherry-pick branch on top of it.
+// Pass alreadyFetched when the target branch was fetched from origin already.
+func (cherryPick *CherryPick) checkoutBranch(ctx context.Context, logger log.Logger, branchName string, alreadyFetched bool) error {
+	if !alreadyFetched {
+		if err := cherryPick.fetchTarget(ctx, logger); err != nil {
 			return err
 		}
 	}
 
+	if !cherryPick.Worktree {
+		state, err := NewState(ctx, branchName)
+		if err != nil {
+			return fmt.Errorf("error saving the state: %w", err)
+		}
+
+		if cherryPick.AutoStash {
+			if dirty, err := IsDirty(ctx); err != nil {
+				return fmt.Errorf("error checking if the repository is dirty: %w", err)
+			} else if dirty {
+				logger.Infof("stashing local changes")
+				if state.StashRef, err = Stash(ctx, fmt.Sprintf("gh-cherry-pick: before checking out %s", branchName)); err != nil {
+					return fmt.Errorf("error stashing local changes: %w", err)
+				} else if state.StashRef == "" {
+					logger.Infof("nothing to stash")
+				} else {
+					logger.WithField("stash", state.StashRef[:7]).Successf("stashed local changes")
+				}
+			}
+		}
+
+		if err = state.Save(ctx); err != nil {
+			return fmt.Errorf("error saving the state: %w", err)
+		}
+		cherryPick.state = state
+	}
+
+	logger.WithField("branch", branchName).
+		WithField("base", cherryPick.OnTo).
+		Infof("checking out to new branch")
+	checkout := func() error { return CheckoutNewBranch(ctx, branchName, "origin", cherryPick.OnTo) }
+	if cherryPick.startCommit != "" {
+		checkout = func() error { return NewCommand("git", "switch", "-c", branchName, cherryPick.startCommit).Run(ctx) }
+	}
+	if err := checkout(); err != nil {
+		return fmt.Errorf("error checking out to new branch '%s': %w", branchName, err)
+	}
+	cherryPick.Result.Branch = branchName
+
 	return nil
 }
+
+// finish pushes the cherry-pick branch. With AutoStash, it then goes back to
+// the original branch and restores the stashed changes.
+func (cherryPick *CherryPick) finish(ctx context.Context, branchName string, desc description) error {
+	if cherryPick.startCommit != "" {
+		desc.Notes = append(desc.Notes, fmt.Sprintf("`%s` was created from `%s` for it.", cherryPick.OnTo, cherryPick.StartPoint))
+	}
+
+	if changed, err := HasDiff(ctx, cherryPick.targetRef(), "HEAD"); err != nil {
+		return fmt.Errorf("error comparing with %s: %w", cherryPick.OnTo, err)
+	} else if !changed {
+		return cherryPick.finishAlreadyPresent(ctx)
+	}
+
+	// A partial forward-port notes it in the commit messages already.
+	if desc.ForwardPort && len(cherryPick.Commits) == 0 && cherryPick.Paths.IsEmpty() {
+		if err := cherryPick.addForwardPortLine(ctx, desc); err != nil {
+			return err
+		}
+	}
+
+	commits, err := RevList(ctx, cherryPick.targetRef()+"..HEAD")
+	if err != nil {
+		return fmt.Errorf("error listing the commits of %s: %w", branchName, err)
+	}
+	cherryPick.Result.Commits = commits
+
+	if err := cherryPick.pushBranch(ctx, branchName, desc); err != nil {
+		return err
+	}
+
+	if cherryPick.state == nil {
+		return nil
+	}
+
+	state := cherryPick.state
+	if !cherryPick.AutoStash {
+		cherryPick.state = nil
+		return RemoveState(ctx)
+	}
+
+	return tui.WithStep(ctx, "restoring local changes", func(ctx context.Context, logger log.Logger) error {
+		if err := restoreState(ctx, logger, state, true); err != nil {
+			return err
+		}
+		cherryPick.state = nil
+		return nil
+	})
+}
+
+// finishAlreadyPresent goes back to the original branch without pushing, as
+// the cherry-pick changes nothing on the target.
+func (cherryPick *CherryPick) finishAlreadyPresent(ctx context.Context) error {
+	logger := log.LoggerFromCtx(ctx)
+	logger.Warnf("the changes are already present on %s. nothing to push", color.Cyan(cherryPick.OnTo))
+	cherryPick.Result.AlreadyPresent = true
+
+	if cherryPick.state == nil {
+		return nil
+	}
+
+	state := cherryPick.state
+	return tui.WithStep(ctx, "cleaning up", func(ctx context.Context, logger log.Logger) error {
+		if err := restoreState(ctx, logger, state, false); err != nil {
+			return err
+		}
+		cherryPick.state = nil
+		return nil
+	})
+}
+
+func (cherryPick *CherryPick) pushBranch(ctx context.Context, branchName string, desc description) error {
+	if !cherryPick.Push && !cherryPick.Worktree && !cherryPick.CreatePR && !cherryPick.Result.Draft {
+		if cherryPick.startCommit != "" {
+			log.LoggerFromCtx(ctx).Warnf("%s is not created on origin, as nothing is pushed. create it with %s before pushing %s",
+				color.Cyan(cherryPick.OnTo), color.Yellow(fmt.Sprintf("`git push origin %s:refs/heads/%s`", cherryPick.startCommit, cherryPick.OnTo)), color.Cyan(branchName))
+		}
+		return nil
+	}
+
+	return tui.WithStep(ctx, "pushing branch", func(ctx context.Context, logger log.Logger) error {
+		if cherryPick.startCommit != "" {
+			if err := cherryPick.createTarget(ctx, logger); err != nil {
+				return err
+			}
+		}
+
+		logger.WithField("branch", branchName).Infof("pushing")
+		if err := Push(ctx, "origin", branchName); err != nil {
+			return fmt.Errorf("error pushing branch %s: %w", branchName, err)
+		}
+
+		if cherryPick.CreatePR || cherryPick.Result.Draft {
+			repo, err := GetRepository(ctx)
+			if err != nil {
+				return fmt.Errorf("error getting the current repository: %w", err)
+			}
+
+			logger.WithField("base", cherryPick.OnTo).Infof("creating a pull request")
+			if cherryPick.Result.PullRequestURL, err = CreatePullRequest(ctx, repo, cherryPick.OnTo, branchName, desc.Title(), desc.Body(), cherryPick.Result.Draft); err != nil {
+				return fmt.Errorf("error creating a pull request for branch %s: %w", branchName, err)
+			}
+
+			logger.Successf("pushed branch %s and created a pull request\n    %s", color.Cyan(branchName), cherryPick.Result.PullRequestURL)
+			cherryPick.copyMetadata(ctx, logger)
+			return nil
+		}
+
+		repoWebURL, repoURLErr := GetRepoWebURL(ctx)
+		if repoURLErr == nil {
+			query := url.Values{"expand": {"1"}, "title": {desc.Title()}, "body": {desc.Body()}}
+			logger.Successf("pushed branch %s\ncreate a pull request by visiting:\n    %s",
+				color.Cyan(branchName),
+				fmt.Sprintf("%s/compare/%s...%s?%s", repoWebURL, cherryPick.OnTo, branchName, query.Encode()),
+			)
+		} else {
+			logger.Successf("pushed branch %s", color.Cyan(branchName))
+		}
+
+		return nil
+	})
+}
+
+// copyMetadata sets the metadata of the original PR on the created PR.
+func (cherryPick *CherryPick) copyMetadata(ctx context.Context, logger log.Logger) {
+	options := cherryPick.Metadata
+	if cherryPick.Result.Draft {
+		// The author is the one to resolve the conflicts.
+		options.AssignAuthor = true
+	}
+	if options.isEmpty() {
+		return
+	}
+
+	if cherryPick.pr != nil && options.Copy {
+		logger.Infof("fetching the metadata of %s", cherryPick.pr.PRNumberString())
+		if err := GetPullRequestFields(ctx, cherryPick.repo, cherryPick.pr, "labels,assignees,reviewRequests,latestReviews,milestone"); err != nil {
+			logger.WithError(err).Warnf("error fetching the metadata. it is not copied")
+			return
+		}
+		if err := GetPullRequestFields(ctx, cherryPick.repo, cherryPick.pr, "projectItems"); err != nil {
+			logger.WithError(err).Warnf("error fetching the projects, which needs the read:project scope. they are not copied")
+		}
+	}
+
+	applyMetadata(ctx, logger, cherryPick.Result.PullRequestURL, options.metadataOf(cherryPick.pr))
+}
+
+// cherryPickCommits runs git cherry-pick for the given commits, resolving
+// conflicts with the resolvers. The returned error reports the conflicts
+// which remain and their prerequisites, with instructions to continue or abort.
+func (cherryPick *CherryPick) cherryPickCommits(ctx context.Context, commits ...string) error {
+	classify := func(err error) error {
+		if err == nil {
+			return nil
+		}
+		helpMsg := fmt.Sprintf("run %v after resolve the conflicts\nrun %v if you want to abort the cherry-pick", color.Green("`git cherry-pick --continue`"), color.Yellow("`git cherry-pick --abort`"))
+
+		var gitError *GitError
+		if errors.As(err, &gitError) && gitError.ExitCode == 1 && strings.Contains(gitError.Stderr, "error: could not apply") {
+			return &ConflictError{message: helpMsg, err: err}
+		}
+		return fmt.Errorf("%s\n\n%w", helpMsg, err)
+	}
+
+	start, err := RevParse(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	args := []string{"cherry-pick", "--keep-redundant-commits"}
+	if cherryPick.RecordOrigin {
+		args = append(args, "-x")
+	}
+	args = append(args, commits...)
+	err = classify(NewCommand("git", args...).Run(ctx))
+	return cherryPick.handleConflicts(ctx, err, start, func() error {
+		return classify(NewCommand("git", "-c", "core.editor=true", "cherry-pick", "--continue").Run(ctx))
+	}, func() error {
+		return cherryPick.cherryPickCommits(ctx, commits...)
+	})
+}
+
+// applyPatches runs git am for the given mailbox, resolving conflicts with
+// the resolvers. The returned error reports the conflicts which remain and
+// their prerequisites, with instructions to continue or abort.
+func (cherryPick *CherryPick) applyPatches(ctx context.Context, mbox io.Reader) error {
+	classify := func(err error) error {
+		if err == nil {
+			return nil
+		}
+		helpMsg := fmt.Sprintf("run %s after resolve the conflicts\nrun %s if you want to abort the rebase", color.Green("`git am --continue`"), color.Yellow("`git am --abort`"))
+
+		var gitError *GitError
+		if errors.As(err, &gitError) && gitError.ExitCode == 1 && strings.Contains(gitError.Stderr, "error: Failed to merge in the changes") {
+			return &ConflictError{message: helpMsg, err: err}
+		}
+		return fmt.Errorf("%s\n\n%w", helpMsg, err)
+	}
+
+	start, err := RevParse(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	// The patches are kept to apply them again after the prerequisites.
+	var patches bytes.Buffer
+	if _, err = patches.ReadFrom(mbox); err != nil {
+		return fmt.Errorf("error reading the patches: %w", err)
+	}
+
+	err = classify(NewCommand("git", "am", "-3").Run(ctx, WithStdin(bytes.NewReader(patches.Bytes()))))
+	return cherryPick.handleConflicts(ctx, err, start, func() error {
+		return classify(NewCommand("git", "am", "--continue").Run(ctx))
+	}, func() error {
+		return cherryPick.applyPatches(ctx, &patches)
+	})
+}
+
+// applySquashed applies the changes from base up to head as a single commit
+// with the given message, resolving conflicts with the resolvers.
+func (cherryPick *CherryPick) applySquashed(ctx context.Context, base, head, message string) error {
+	var diff bytes.Buffer
+	if err := NewCommand("git", "diff", "--binary", base, head).Run(ctx, WithStdout(&diff)); err != nil {
+		return fmt.Errorf("error getting the diff: %w", err)
+	}
+
+	start, err := RevParse(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	if err = NewCommand("git", "apply", "--3way", "--index").Run(ctx, WithStdin(&diff)); err != nil {
+		helpMsg := fmt.Sprintf("run %v after resolve the conflicts\nrun %v if you want to abort", color.Green(fmt.Sprintf("`git commit -m %q`", message)), color.Yellow("`git reset --hard`"))
+
+		var gitError *GitError
+		if errors.As(err, &gitError) && gitError.ExitCode == 1 && strings.Contains(gitError.Stderr, "with conflicts") {
+			err = &ConflictError{message: helpMsg, err: err}
+		} else {
+			return fmt.Errorf("%s\n\n%w", helpMsg, err)
+		}
+		return cherryPick.handleConflicts(ctx, err, start, func() error {
+			return NewCommand("git", "commit", "-m", message).Run(ctx)
+		}, func() error {
+			return cherryPick.applySquashed(ctx, base, head, message)
+		})
+	}
+
+	return NewCommand("git", "commit", "-m", message).Run(ctx)
+}
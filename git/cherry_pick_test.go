@@ -5,8 +5,12 @@ import (
 	"errors"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/log"
 )
 
 func setup(ctx context.Context) func() {
@@ -104,3 +108,40 @@ func TestRunWithContext(t *testing.T) {
 		})
 	}
 }
+
+func TestSkipMergeCommits(t *testing.T) {
+	ctx, sh := newTestRepo(t)
+	// The PR merges its base branch in between its commits.
+	sh("git checkout -q feature && git merge -q --no-ff -m 'Merge main' main && echo c > c.txt && git add c.txt && git commit -qm 'add c'")
+	var prCommits []gitobj.Commit
+	for _, oid := range strings.Fields(sh("git rev-list --reverse main..feature")) {
+		prCommits = append(prCommits, gitobj.Commit{Oid: oid})
+	}
+
+	testcases := []struct {
+		name     string
+		commits  []gitobj.Commit
+		expected []gitobj.Commit
+		wantErr  bool
+	}{{
+		name:     "merge of the base branch",
+		commits:  prCommits,
+		expected: []gitobj.Commit{prCommits[0], prCommits[1], prCommits[3]},
+	}, {
+		name:    "only merge commits",
+		commits: prCommits[2:3],
+		wantErr: true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			commits, err := skipMergeCommits(ctx, log.NewLogger(), tc.commits)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if !slices.Equal(commits, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, commits)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/cli/safeexec"
 )
@@ -37,6 +38,14 @@ func (c *Command) Run(ctx context.Context, mods ...CommandModifier) error {
 	}
 
 	cmd := exec.CommandContext(ctx, exe, c.args...)
+	cmd.Dir = DirFromCtx(ctx)
+	if auth := authFromCtx(ctx); auth != nil {
+		env, err := authEnv(ctx, c.cmd, auth)
+		if err != nil {
+			return err
+		}
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -66,6 +75,12 @@ func (c *Command) Run(ctx context.Context, mods ...CommandModifier) error {
 				ge.ExitCode = exitError.ExitCode()
 			}
 			return &ge
+
+		case "sh":
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%w: %s", err, msg)
+			}
+			return err
 		default:
 			panic(fmt.Sprintf("unsupported command: %s", c.cmd))
 		}
@@ -74,6 +89,21 @@ func (c *Command) Run(ctx context.Context, mods ...CommandModifier) error {
 	return nil
 }
 
+type dirKey struct{}
+
+// CtxWithDir makes the commands run with the returned context run in dir
+// instead of the current directory.
+func CtxWithDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, dirKey{}, dir)
+}
+
+// DirFromCtx returns the directory set by CtxWithDir, or an empty string for
+// the current directory.
+func DirFromCtx(ctx context.Context) string {
+	dir, _ := ctx.Value(dirKey{}).(string)
+	return dir
+}
+
 type CommandModifier func(c *exec.Cmd)
 
 func WithStdout(stdout io.Writer) CommandModifier {
@@ -97,6 +127,8 @@ func path(cmd string) (string, error) {
 			return ghExe, nil
 		}
 		return safeexec.LookPath("gh")
+	case "sh":
+		return safeexec.LookPath("sh")
 	}
 
 	return "", fmt.Errorf("unsupported command: %s", cmd)
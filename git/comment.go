@@ -0,0 +1,88 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// comment sets the outcome of the cherry-pick onto OnTo in the backports
+// comment of the PR. Failing to comment does not fail the cherry-pick.
+func (cherryPick *CherryPick) comment(ctx context.Context, err error) {
+	logger := log.LoggerFromCtx(ctx)
+
+	status := cherryPick.status(ctx, err)
+	// The targets of a Parallel update the same comment.
+	unlock := lockShared(ctx)
+	commentErr := UpdateStickyComment(ctx, cherryPick.repo, cherryPick.pr.Number, cherryPick.OnTo, status)
+	unlock()
+	if commentErr != nil {
+		logger.WithError(commentErr).Warnf("error commenting on %s", cherryPick.pr.PRNumberString())
+		return
+	}
+	logger.Successf("commented the result on %s", cherryPick.pr.PRNumberString())
+}
+
+func (cherryPick *CherryPick) status(ctx context.Context, err error) string {
+	outcome := Outcome{
+		Branch:          cherryPick.Result.Branch,
+		PullRequestURL:  cherryPick.Result.PullRequestURL,
+		AlreadyPresent:  cherryPick.Result.AlreadyPresent,
+		Draft:           cherryPick.Result.Draft,
+		ConflictedFiles: cherryPick.Result.ConflictedFiles,
+	}
+
+	var conflictErr *ConflictError
+	if outcome.Conflicted = errors.As(err, &conflictErr); outcome.Conflicted {
+		outcome.ConflictedFiles, _ = ConflictedFiles(ctx)
+	} else {
+		outcome.Err = err
+	}
+	return outcome.Status()
+}
+
+// Outcome is how a cherry-pick onto a target ended, as the backports comment
+// on the PR and the reports of the bot show it.
+type Outcome struct {
+	Branch         string
+	PullRequestURL string
+	AlreadyPresent bool
+	// Draft is set when the conflicts in ConflictedFiles are pushed as a draft PR.
+	Draft           bool
+	Conflicted      bool
+	ConflictedFiles []string
+	Err             error
+}
+
+// Status renders the outcome for a cell of a markdown table, e.g. "✅ <PR URL>".
+func (o Outcome) Status() string {
+	switch {
+	case o.Conflicted && len(o.ConflictedFiles) > 0:
+		return fmt.Sprintf("⚠️ conflicts in %s", CodeList(o.ConflictedFiles))
+	case o.Conflicted:
+		return "⚠️ conflicts"
+	case o.Err != nil:
+		line, _, _ := strings.Cut(o.Err.Error(), "\n")
+		return fmt.Sprintf("❌ %s", strings.ReplaceAll(line, "|", "\\|"))
+	case o.AlreadyPresent:
+		return "☑️ already present"
+	case o.Draft:
+		return fmt.Sprintf("📝 draft %s with conflicts in %s", o.PullRequestURL, CodeList(o.ConflictedFiles))
+	case o.PullRequestURL != "":
+		return fmt.Sprintf("✅ %s", o.PullRequestURL)
+	default:
+		return fmt.Sprintf("✅ cherry-picked onto branch `%s`", o.Branch)
+	}
+}
+
+// CodeList renders items as a comma-separated list of code spans.
+func CodeList(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, item := range items {
+		quoted = append(quoted, "`"+item+"`")
+	}
+	return strings.Join(quoted, ", ")
+}
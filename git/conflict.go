@@ -0,0 +1,130 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+// ConflictAction is what to do when cherry-picking stops on conflicts.
+type ConflictAction string
+
+const (
+	// ConflictActionStop leaves the conflicts to be resolved locally.
+	ConflictActionStop ConflictAction = "stop"
+	// ConflictActionDraftPR commits the conflicts with their markers, and
+	// opens a draft pull request for the author of the PR to resolve them.
+	ConflictActionDraftPR ConflictAction = "draft-pr"
+)
+
+func (a ConflictAction) Validate() error {
+	switch a {
+	case ConflictActionStop, ConflictActionDraftPR:
+		return nil
+	default:
+		return fmt.Errorf("invalid conflict action %q: must be one of stop, draft-pr", a)
+	}
+}
+
+// maxConflictedSteps bounds the commits committed with conflict markers, in
+// case continuing never finishes.
+const maxConflictedSteps = 1000
+
+// pushConflicts commits the conflicts of the stopped cherry-pick with their
+// markers, and pushes them as a draft pull request.
+func (cherryPick *CherryPick) pushConflicts(ctx context.Context) error {
+	branchName := cherryPick.Result.Branch
+
+	err := tui.WithStep(ctx, "committing the conflicts", func(ctx context.Context, logger log.Logger) error {
+		files, err := cherryPick.commitConflicts(ctx, logger)
+		if err != nil {
+			return err
+		}
+		cherryPick.Result.ConflictedFiles = files
+		logger.Successf("committed conflicts in %d file(s) with their markers", len(files))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	desc := *cherryPick.desc
+	desc.Warnings = append(desc.Warnings, "This cherry-pick **has conflicts**, which are committed with their conflict markers. Resolve them before marking it ready for review.")
+	desc.Notes = append(desc.Notes, conflictInstructions(branchName, cherryPick.Result.ConflictedFiles))
+	cherryPick.Result.Draft = true
+
+	return cherryPick.finish(ctx, branchName, desc)
+}
+
+// commitConflicts stages the conflicted files as they are, markers included,
+// and continues the cherry-pick until it is done. Later commits may conflict
+// again, so it returns the conflicted files of every step.
+func (cherryPick *CherryPick) commitConflicts(ctx context.Context, logger log.Logger) ([]string, error) {
+	var files []string
+	for range maxConflictedSteps {
+		conflicted, err := ConflictedFiles(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing the conflicted files: %w", err)
+		}
+		for _, file := range conflicted {
+			if !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+		logger.WithField("files", strings.Join(conflicted, ", ")).Infof("committing conflicts")
+
+		if err = NewCommand("git", "add", "--all").Run(ctx); err != nil {
+			return nil, fmt.Errorf("error staging the conflicts: %w", err)
+		}
+
+		inCherryPick, err := IsInCherryPick(ctx)
+		if err != nil {
+			return nil, err
+		}
+		inAm, err := IsInRebaseOrAm(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case inCherryPick:
+			err = NewCommand("git", "-c", "core.editor=true", "cherry-pick", "--continue").Run(ctx)
+		case inAm:
+			err = NewCommand("git", "am", "--continue").Run(ctx)
+		default:
+			// applySquashed stopped on conflicts, which leaves no operation to continue.
+			message := fmt.Sprintf("%s (%s)", cherryPick.pr.Title, cherryPick.desc.PRRef)
+			err = NewCommand("git", "commit", "--allow-empty", "-m", message).Run(ctx)
+		}
+		if err == nil {
+			return files, nil
+		}
+
+		if conflicted, _ := ConflictedFiles(ctx); len(conflicted) == 0 {
+			return nil, fmt.Errorf("error continuing after committing the conflicts: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("cherry-picking still conflicts after %d commits", maxConflictedSteps)
+}
+
+// conflictInstructions tells how to resolve the conflicts of the draft pull request locally.
+func conflictInstructions(branchName string, files []string) string {
+	var sb strings.Builder
+	sb.WriteString("Conflicting files:\n\n")
+	for _, file := range files {
+		fmt.Fprintf(&sb, "- `%s`\n", file)
+	}
+	sb.WriteString("\nTo resolve the conflicts locally:\n\n```shell\n")
+	fmt.Fprintf(&sb, "git fetch origin %s\n", branchName)
+	fmt.Fprintf(&sb, "git switch %s\n", branchName)
+	sb.WriteString("# resolve the conflict markers (<<<<<<<, =======, >>>>>>>) in the files above, then:\n")
+	fmt.Fprintf(&sb, "git add %s\n", strings.Join(files, " "))
+	sb.WriteString("git commit -m \"Resolve conflicts\"\n")
+	fmt.Fprintf(&sb, "git push origin %s\n", branchName)
+	sb.WriteString("```")
+	return sb.String()
+}
@@ -0,0 +1,324 @@
+package git
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/internal/color"
+)
+
+// ConflictType is how a path conflicts, seen from the target branch ("ours")
+// and the cherry-picked changes ("theirs").
+type ConflictType string
+
+const (
+	// ConflictContent is a path changed on both sides in overlapping lines.
+	ConflictContent ConflictType = "content"
+	// ConflictAddAdd is a path added on both sides with different contents.
+	ConflictAddAdd ConflictType = "add/add"
+	// ConflictModifyDelete is a path changed on the target and deleted by the changes.
+	ConflictModifyDelete ConflictType = "modify/delete"
+	// ConflictDeleteModify is a path deleted on the target and changed by the changes.
+	ConflictDeleteModify ConflictType = "delete/modify"
+	// ConflictRename is a path renamed on one side, and changed, deleted or
+	// renamed differently on the other.
+	ConflictRename ConflictType = "rename"
+)
+
+// conflictTypes maps the XY status of the unmerged entries of git status.
+var conflictTypes = map[string]ConflictType{
+	"UU": ConflictContent,
+	"AA": ConflictAddAdd,
+	"UD": ConflictModifyDelete,
+	"DU": ConflictDeleteModify,
+	"AU": ConflictRename,
+	"UA": ConflictRename,
+	"DD": ConflictRename,
+}
+
+// ConflictCommit is a commit of the target branch.
+type ConflictCommit struct {
+	SHA     string
+	Subject string
+}
+
+// Conflict is a conflicting path.
+type Conflict struct {
+	Path string
+	Type ConflictType
+	// Hunks is the number of conflicting hunks of a content conflict.
+	Hunks int
+	// Commits are the last commits of the target branch which touched the
+	// lines of each hunk, or the path when the lines are unknown. A missing
+	// prerequisite PR is often the PR of one of them.
+	Commits []ConflictCommit
+}
+
+func (c Conflict) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (%s", c.Path, c.Type)
+	if c.Hunks > 0 {
+		fmt.Fprintf(&sb, ", %d hunk(s)", c.Hunks)
+	}
+	sb.WriteString(")")
+	for i, commit := range c.Commits {
+		if i == 0 {
+			sb.WriteString(", last changed on the target by ")
+		} else {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%s %q", commit.SHA[:min(7, len(commit.SHA))], commit.Subject)
+	}
+	return sb.String()
+}
+
+// AnalyzeConflicts analyzes the unmerged paths of the stopped cherry-pick.
+func AnalyzeConflicts(ctx context.Context) ([]Conflict, error) {
+	root, err := GetRepoRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Paths of git status are relative to the top of the worktree.
+	ctx = CtxWithDir(ctx, root)
+
+	stdout := &bytes.Buffer{}
+	if err = NewCommand("git", "status", "--porcelain=v2", "-z", "--untracked-files=no").Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, fmt.Errorf("error getting the status: %w", err)
+	}
+
+	var conflicts []Conflict
+	for _, c := range parseUnmerged(stdout.String()) {
+		if c.Type == ConflictContent || c.Type == ConflictAddAdd {
+			hunks, err := conflictHunks(filepath.Join(root, c.Path))
+			if err != nil {
+				return nil, err
+			}
+			c.Hunks = len(hunks)
+			c.Commits = blameHunks(ctx, c.Path, hunks)
+		}
+		if len(c.Commits) == 0 {
+			if commit := lastCommitOf(ctx, c.Path); commit != nil {
+				c.Commits = []ConflictCommit{*commit}
+			}
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, nil
+}
+
+// parseUnmerged returns the unmerged entries of git status --porcelain=v2 -z,
+// which are "u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>".
+func parseUnmerged(status string) []Conflict {
+	var conflicts []Conflict
+	entries := strings.Split(status, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if strings.HasPrefix(entry, "2 ") {
+			// A rename is followed by its original path.
+			i++
+			continue
+		}
+
+		fields := strings.SplitN(entry, " ", 11)
+		if fields[0] != "u" || len(fields) != 11 {
+			continue
+		}
+		conflictType, ok := conflictTypes[fields[1]]
+		if !ok {
+			conflictType = ConflictContent
+		}
+		conflicts = append(conflicts, Conflict{Path: fields[10], Type: conflictType})
+	}
+	return conflicts
+}
+
+// conflictHunk is a conflict hunk, with the lines of the target branch and,
+// in the diff3 conflict style, those of the merge base.
+type conflictHunk struct {
+	ours []string
+	base []string
+}
+
+// conflictHunks returns the conflict hunks of the file.
+func conflictHunks(file string) ([]conflictHunk, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseConflictHunks(string(content)), nil
+}
+
+// parseConflictHunks parses the conflict markers, in the merge or the diff3
+// conflict style.
+func parseConflictHunks(content string) []conflictHunk {
+	var hunks []conflictHunk
+	var hunk *conflictHunk
+	var section *[]string
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "<<<<<<<"):
+			hunk = &conflictHunk{ours: []string{}, base: []string{}}
+			section = &hunk.ours
+		case hunk != nil && strings.HasPrefix(line, "|||||||"):
+			section = &hunk.base
+		case hunk != nil && strings.HasPrefix(line, "======="):
+			section = nil
+		case hunk != nil && strings.HasPrefix(line, ">>>>>>>"):
+			hunks = append(hunks, *hunk)
+			hunk, section = nil, nil
+		case section != nil:
+			*section = append(*section, line)
+		}
+	}
+	return hunks
+}
+
+// blameBlocks blames each block of lines of path at tip, where it is searched
+// after the previous block. With since, the lines older than since are blamed
+// on boundary commits. The commits of a block not found are empty.
+func blameBlocks(ctx context.Context, path, since, tip string, blocks [][]string) [][]blamedCommit {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "show", tip+":"+path).Run(ctx, WithStdout(stdout)); err != nil {
+		return make([][]blamedCommit, len(blocks))
+	}
+	lines := strings.Split(stdout.String(), "\n")
+
+	revision := tip
+	if since != "" {
+		revision = since + ".." + tip
+	}
+
+	blamed := make([][]blamedCommit, len(blocks))
+	offset := 0
+	for i, block := range blocks {
+		start := indexOfLines(lines[offset:], block)
+		if len(block) == 0 || start < 0 {
+			continue
+		}
+		start += offset
+		offset = start + len(block)
+
+		blame := &bytes.Buffer{}
+		lineRange := fmt.Sprintf("%d,%d", start+1, start+len(block))
+		if err := NewCommand("git", "blame", "--porcelain", "-L", lineRange, revision, "--", path).Run(ctx, WithStdout(blame)); err == nil {
+			blamed[i] = parseBlame(blame.String())
+		}
+	}
+	return blamed
+}
+
+// blameHunks returns the newest commit of the target branch which touched
+// the lines of each hunk found in HEAD.
+func blameHunks(ctx context.Context, path string, hunks []conflictHunk) []ConflictCommit {
+	blocks := make([][]string, 0, len(hunks))
+	for _, hunk := range hunks {
+		blocks = append(blocks, hunk.ours)
+	}
+
+	var commits []ConflictCommit
+	for _, blamed := range blameBlocks(ctx, path, "", "HEAD", blocks) {
+		if len(blamed) == 0 {
+			continue
+		}
+		newest := slices.MaxFunc(blamed, func(a, b blamedCommit) int { return cmp.Compare(a.time, b.time) })
+		if !slices.ContainsFunc(commits, func(c ConflictCommit) bool { return c.SHA == newest.sha }) {
+			commits = append(commits, ConflictCommit{SHA: newest.sha, Subject: newest.summary})
+		}
+	}
+	return commits
+}
+
+// indexOfLines returns the index of the first occurrence of sub in lines, or -1.
+func indexOfLines(lines, sub []string) int {
+	for i := 0; i+len(sub) <= len(lines); i++ {
+		if slices.Equal(lines[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+type blamedCommit struct {
+	sha     string
+	time    int64
+	summary string
+	// boundary is set on the commits at the boundary of a blamed range,
+	// which the lines are older than.
+	boundary bool
+}
+
+// parseBlame returns the commits of git blame --porcelain, whose details
+// follow the first line blamed on each of them.
+func parseBlame(blame string) []blamedCommit {
+	var commits []blamedCommit
+	current := -1
+	for _, line := range strings.Split(blame, "\n") {
+		if fields := strings.Fields(line); len(fields) >= 3 && len(fields[0]) == 40 && !strings.HasPrefix(line, "\t") {
+			current = slices.IndexFunc(commits, func(c blamedCommit) bool { return c.sha == fields[0] })
+			if current < 0 {
+				commits = append(commits, blamedCommit{sha: fields[0]})
+				current = len(commits) - 1
+			}
+			continue
+		}
+		if current < 0 {
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "committer-time "); ok {
+			commits[current].time, _ = strconv.ParseInt(value, 10, 64)
+		} else if value, ok := strings.CutPrefix(line, "summary "); ok {
+			commits[current].summary = value
+		} else if line == "boundary" {
+			commits[current].boundary = true
+		}
+	}
+	return commits
+}
+
+// lastCommitOf returns the last commit of the target branch which touched
+// the path, or nil.
+func lastCommitOf(ctx context.Context, path string) *ConflictCommit {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "log", "-1", "--format=%H%x00%s", "HEAD", "--", path).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil
+	}
+	sha, subject, ok := strings.Cut(strings.TrimSpace(stdout.String()), "\x00")
+	if !ok {
+		return nil
+	}
+	return &ConflictCommit{SHA: sha, Subject: subject}
+}
+
+// reportConflicts adds the report of the conflicting paths, and the
+// suggestion if any, to a ConflictError. The report is best effort: when
+// analyzing fails, it is left out.
+func reportConflicts(ctx context.Context, err error, suggestion string) error {
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) {
+		return err
+	}
+
+	var sb strings.Builder
+	if conflicts, analyzeErr := AnalyzeConflicts(ctx); analyzeErr == nil && len(conflicts) > 0 {
+		fmt.Fprintf(&sb, "conflicts in %d file(s):\n", len(conflicts))
+		for _, c := range conflicts {
+			fmt.Fprintf(&sb, "  • %s\n", color.Cyan(c.String()))
+		}
+		sb.WriteString("\n")
+		conflictErr = &ConflictError{message: conflictErr.message, err: conflictErr.err, conflicts: conflicts}
+	}
+	if suggestion != "" {
+		sb.WriteString(suggestion + "\n")
+	}
+	return &ConflictError{message: sb.String() + conflictErr.message, err: conflictErr.err, conflicts: conflictErr.conflicts}
+}
@@ -0,0 +1,85 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUnmerged(t *testing.T) {
+	status := "1 M. N... 100644 100644 100644 aaa bbb clean.go\x00" +
+		"u UU N... 100644 100644 100644 100644 h1 h2 h3 dir/with space.go\x00" +
+		"2 R. N... 100644 100644 100644 aaa bbb R100 new.go\x00old.go\x00" +
+		"u DU N... 100644 000000 100644 100644 h1 h2 h3 deleted.go\x00" +
+		"u UD N... 100644 100644 000000 100644 h1 h2 h3 modified.go\x00" +
+		"u AU N... 000000 100644 000000 100644 h1 h2 h3 renamed.go\x00"
+
+	expected := []Conflict{
+		{Path: "dir/with space.go", Type: ConflictContent},
+		{Path: "deleted.go", Type: ConflictDeleteModify},
+		{Path: "modified.go", Type: ConflictModifyDelete},
+		{Path: "renamed.go", Type: ConflictRename},
+	}
+	if actual := parseUnmerged(status); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestParseBlame(t *testing.T) {
+	older := "1111111111111111111111111111111111111111"
+	newer := "2222222222222222222222222222222222222222"
+	blame := older + " 2 2 1\n" +
+		"author a\n" +
+		"committer-time 100\n" +
+		"summary Add the feature\n" +
+		"boundary\n" +
+		"filename a.go\n" +
+		"\tline two\n" +
+		newer + " 3 3 2\n" +
+		"committer-time 200\n" +
+		"summary Fix the feature\n" +
+		"previous " + older + " a.go\n" +
+		"filename a.go\n" +
+		"\tline three\n" +
+		newer + " 4 4\n" +
+		"\tline four\n"
+
+	expected := []blamedCommit{
+		{sha: older, time: 100, summary: "Add the feature", boundary: true},
+		{sha: newer, time: 200, summary: "Fix the feature"},
+	}
+	if actual := parseBlame(blame); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestParseConflictHunks(t *testing.T) {
+	testcases := []struct {
+		name     string
+		content  string
+		expected []conflictHunk
+	}{{
+		name:    "merge style",
+		content: "a\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> abc (Fix)\nb\n<<<<<<< HEAD\n=======\nadded\n>>>>>>> abc (Fix)\n",
+		expected: []conflictHunk{
+			{ours: []string{"ours"}, base: []string{}},
+			{ours: []string{}, base: []string{}},
+		},
+	}, {
+		name:    "diff3 style",
+		content: "<<<<<<< ours\none\ntwo\n||||||| base\nbase\n=======\ntheirs\n>>>>>>> theirs\n",
+		expected: []conflictHunk{
+			{ours: []string{"one", "two"}, base: []string{"base"}},
+		},
+	}, {
+		name:    "no conflicts",
+		content: "a\n=======\nb\n",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := parseConflictHunks(tc.content); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
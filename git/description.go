@@ -0,0 +1,74 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+// description holds what is needed to describe a cherry-pick branch in the
+// title and body of its pull request.
+type description struct {
+	// PR is the cherry-picked pull request. It is nil for commit ranges.
+	PR *gitobj.PullRequest
+	// PRRef refers to PR from the target repository, e.g. #123 or owner/repo#123.
+	PRRef string
+	// CommitRange is set instead of PR for changes without a pull request.
+	CommitRange string
+	OnTo        string
+	// ForwardPort describes a forward-port of PR onto a newer line.
+	ForwardPort bool
+	// Revert describes the revert of the backport of PR instead.
+	Revert bool
+	// Warnings are rendered as an alert at the top of the body.
+	Warnings []string
+	// Notes are rendered as paragraphs after the summary.
+	Notes []string
+}
+
+func (d description) Title() string {
+	if d.PR == nil {
+		return fmt.Sprintf("[%s] Cherry-pick %s", d.OnTo, d.CommitRange)
+	}
+	if d.Revert {
+		return fmt.Sprintf("[%s] Revert %q", d.OnTo, d.PR.Title)
+	}
+	return fmt.Sprintf("[%s] %s", d.OnTo, d.PR.Title)
+}
+
+// kind names the change in the notes, e.g. "Partial cherry-pick of #123.".
+func (d description) kind() string {
+	if d.ForwardPort {
+		return "forward-port"
+	}
+	return "cherry-pick"
+}
+
+func (d description) Body() string {
+	var sb strings.Builder
+
+	if len(d.Warnings) > 0 {
+		sb.WriteString("> [!WARNING]\n")
+		for _, warning := range d.Warnings {
+			fmt.Fprintf(&sb, "> %s\n", warning)
+		}
+		sb.WriteString("\n")
+	}
+
+	if d.PR == nil {
+		fmt.Fprintf(&sb, "Cherry-pick of `%s` onto `%s`.\n", d.CommitRange, d.OnTo)
+	} else if d.Revert {
+		fmt.Fprintf(&sb, "Revert of the backport of %s on `%s`.\n", d.PRRef, d.OnTo)
+	} else if d.ForwardPort {
+		fmt.Fprintf(&sb, "Forward-port of %s from `%s` onto `%s`.\n", d.PRRef, d.PR.BaseRefName, d.OnTo)
+	} else {
+		fmt.Fprintf(&sb, "Cherry-pick of %s onto `%s`.\n", d.PRRef, d.OnTo)
+	}
+
+	for _, note := range d.Notes {
+		fmt.Fprintf(&sb, "\n%s\n", note)
+	}
+
+	return sb.String()
+}
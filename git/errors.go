@@ -50,3 +50,24 @@ func (ge *GHError) Error() string {
 func (ge *GHError) Unwrap() error {
 	return ge.err
 }
+
+// ConflictError is returned when cherry-picking stops on conflicts, which
+// have to be resolved manually.
+type ConflictError struct {
+	message   string
+	err       error
+	conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return e.message
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.err
+}
+
+// Conflicts returns the analyzed conflicting paths, if any.
+func (e *ConflictError) Conflicts() []Conflict {
+	return e.conflicts
+}
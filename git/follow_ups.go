@@ -0,0 +1,154 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+// FollowUp is a later commit on the base branch of a PR which refers to the
+// PR, e.g. "Fix the fix of #123", or reverts it.
+type FollowUp struct {
+	SHA     string
+	Subject string
+	// Number is the PR of the commit, or 0 when it has none.
+	Number int
+	// Revert is set when the commit reverts a commit of the PR.
+	Revert bool
+}
+
+// Ref refers to the follow-up by its PR, or by its commit when it has none.
+func (f FollowUp) Ref() string {
+	if f.Number != 0 {
+		return fmt.Sprintf("#%d", f.Number)
+	}
+	return f.SHA[:min(7, len(f.SHA))]
+}
+
+// revertPattern matches the message of git revert, e.g. "This reverts commit
+// <sha>.", which the revert button of GitHub uses too.
+var revertPattern = regexp.MustCompile(`This reverts commit ([0-9a-f]{7,40})`)
+
+// findFollowUps returns the commits of baseRef after the merge commit of the
+// PR which refer to it or revert it, oldest first. prCommits are the commits
+// of the PR on baseRef, which a revert refers to.
+func (cherryPick *CherryPick) findFollowUps(ctx context.Context, baseRef string, prCommits []string) ([]FollowUp, error) {
+	pr := cherryPick.pr
+	stdout := &bytes.Buffer{}
+	revisionRange := pr.MergeCommit.Sha + ".." + baseRef
+	if err := NewCommand("git", "log", "--reverse", "--no-merges", "--format=%H%x00%s%x00%B%x1e", revisionRange).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, fmt.Errorf("error listing the commits of %s: %w", revisionRange, err)
+	}
+
+	var followUps []FollowUp
+	for _, entry := range strings.Split(stdout.String(), "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(entry), "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		followUp := FollowUp{SHA: fields[0], Subject: fields[1]}
+		if followUp.Revert = revertsAny(fields[2], prCommits); !followUp.Revert && !refersTo(fields[2], pr.Number) {
+			continue
+		}
+
+		// Without its PR, the follow-up is still referred to by its commit.
+		followUp.Number, _ = GetPullRequestNumberForCommit(ctx, cherryPick.repo, followUp.SHA)
+		followUps = append(followUps, followUp)
+	}
+	return followUps, nil
+}
+
+// revertsAny reports whether a commit message reverts one of the commits.
+func revertsAny(message string, commits []string) bool {
+	for _, match := range revertPattern.FindAllStringSubmatch(message, -1) {
+		if slices.ContainsFunc(commits, func(commit string) bool { return strings.HasPrefix(commit, match[1]) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// refersTo reports whether a commit message refers to the PR, as #123 or by
+// its URL.
+func refersTo(message string, number int) bool {
+	pattern := fmt.Sprintf(`(^|[^\w/&])#%d\b|/pull/%d\b`, number, number)
+	matched, _ := regexp.MatchString(pattern, message)
+	return matched
+}
+
+// checkFollowUps warns about the reverts and the follow-ups of the PR, in
+// the log and in desc, and keeps the follow-ups to include with
+// IncludeFollowUps. Looking for them is best effort.
+func (cherryPick *CherryPick) checkFollowUps(ctx context.Context, baseRef string, mergeStrategy MergeStrategy, desc *description) error {
+	return tui.WithStep(ctx, "checking for follow-ups and reverts", func(ctx context.Context, logger log.Logger) error {
+		pr := cherryPick.pr
+		prCommits := []string{pr.MergeCommit.Sha}
+		if mergeStrategy == MergeStrategyRebase && len(pr.Commits) > 1 {
+			rebased, err := RevList(ctx, fmt.Sprintf("%s~%d..%s", pr.MergeCommit.Sha, len(pr.Commits), pr.MergeCommit.Sha))
+			if err != nil {
+				logger.WithError(err).Warnf("could not list the commits of %s", desc.PRRef)
+			}
+			prCommits = append(prCommits, rebased...)
+		}
+
+		followUps, err := cherryPick.findFollowUps(ctx, baseRef, prCommits)
+		if err != nil {
+			logger.WithError(err).Warnf("could not look for follow-ups and reverts")
+			return nil
+		}
+
+		var refs []string
+		for _, followUp := range followUps {
+			if followUp.Revert {
+				logger.WithField("commit", followUp.SHA[:7]).Warnf("%s was reverted on %s by %s %s", desc.PRRef, pr.BaseRefName, followUp.Ref(), followUp.Subject)
+				desc.Warnings = append(desc.Warnings, fmt.Sprintf("%s was **reverted** on `%s` by %s.", desc.PRRef, pr.BaseRefName, followUp.Ref()))
+				continue
+			}
+			logger.WithField("commit", followUp.SHA[:7]).Warnf("%s %s follows up on %s", followUp.Ref(), followUp.Subject, desc.PRRef)
+			cherryPick.followUps = append(cherryPick.followUps, followUp)
+			refs = append(refs, followUp.Ref())
+		}
+		if len(refs) == 0 {
+			logger.Successf("no follow-ups or reverts found")
+			return nil
+		}
+
+		if cherryPick.IncludeFollowUps {
+			desc.Notes = append(desc.Notes, fmt.Sprintf("Includes the follow-up(s) %s of %s.", strings.Join(refs, ", "), desc.PRRef))
+		} else {
+			logger.Warnf("run again with %s to include the follow-ups", color.Yellow("-include-follow-ups"))
+			desc.Notes = append(desc.Notes, fmt.Sprintf("The later change(s) %s on `%s` refer to %s, and are not included.", strings.Join(refs, ", "), pr.BaseRefName, desc.PRRef))
+			cherryPick.followUps = nil
+		}
+		return nil
+	})
+}
+
+// applyFollowUps cherry-picks the follow-ups kept by checkFollowUps on top of
+// the PR.
+func (cherryPick *CherryPick) applyFollowUps(ctx context.Context) error {
+	if len(cherryPick.followUps) == 0 {
+		return nil
+	}
+
+	return tui.WithStep(ctx, "cherry-picking follow-ups", func(ctx context.Context, logger log.Logger) error {
+		commits := make([]string, 0, len(cherryPick.followUps))
+		for _, followUp := range cherryPick.followUps {
+			commits = append(commits, followUp.SHA)
+		}
+
+		logger.WithField("commits", len(commits)).Infof("cherry-picking")
+		if err := cherryPick.cherryPickCommits(ctx, commits...); err != nil {
+			return fmt.Errorf("error cherry-picking the follow-ups\n%w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,49 @@
+package git
+
+import "testing"
+
+func TestRefersTo(t *testing.T) {
+	testcases := []struct {
+		message  string
+		expected bool
+	}{
+		{message: "Fix the fix of #123", expected: true},
+		{message: "Follow-up (#123)", expected: true},
+		{message: "See https://github.com/owner/repo/pull/123", expected: true},
+		{message: "#123: handle nil", expected: true},
+		{message: "Fix #1234", expected: false},
+		{message: "Fix owner/other#123", expected: false},
+		{message: "Escape &#123;", expected: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.message, func(t *testing.T) {
+			if actual := refersTo(tc.message, 123); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRevertsAny(t *testing.T) {
+	commits := []string{"0123456789abcdef0123456789abcdef01234567"}
+
+	testcases := []struct {
+		name     string
+		message  string
+		expected bool
+	}{
+		{name: "full sha", message: "Revert \"Fix\"\n\nThis reverts commit 0123456789abcdef0123456789abcdef01234567.", expected: true},
+		{name: "short sha", message: "This reverts commit 0123456.", expected: true},
+		{name: "other commit", message: "This reverts commit fedcba9876543210fedcba9876543210fedcba98.", expected: false},
+		{name: "no revert", message: "Fix 0123456789abcdef", expected: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := revertsAny(tc.message, commits); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
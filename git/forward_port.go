@@ -0,0 +1,99 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+// versionPattern matches the version of a release branch, e.g. 1.2 in
+// release/1.2 or v1.2.x.
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)*`)
+
+// versionOf returns the numbers of the last version in a branch name, or nil
+// when it has none.
+func versionOf(branch string) []int {
+	matches := versionPattern.FindAllString(branch, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var version []int
+	for _, part := range strings.Split(matches[len(matches)-1], ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		version = append(version, n)
+	}
+	return version
+}
+
+// GetDefaultBranch returns the default branch of the repository.
+func GetDefaultBranch(ctx context.Context, repo gitobj.Repository) (string, error) {
+	branch, err := ghAPIQuery(ctx, repo.Host, fmt.Sprintf("repos/%s", repo.NameWithOwner()), ".default_branch", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the default branch: %w", err)
+	}
+	return branch, nil
+}
+
+// checkNewerLine verifies that OnTo is a newer line than the base branch of
+// the PR, which is the default branch or a release branch of a later version.
+// When neither branch tells, it only warns.
+func (cherryPick *CherryPick) checkNewerLine(ctx context.Context, repo gitobj.Repository, pr *gitobj.PullRequest) error {
+	return tui.WithStep(ctx, "checking the target is a newer line", func(ctx context.Context, logger log.Logger) error {
+		base, target := pr.BaseRefName, cherryPick.OnTo
+		if target == base {
+			return fmt.Errorf("%s is the base branch of the PR. forward-port onto a newer line", target)
+		}
+
+		defaultBranch, err := GetDefaultBranch(ctx, repo)
+		if err != nil {
+			return err
+		}
+		if base == defaultBranch {
+			return fmt.Errorf("the PR was merged into the default branch %s, which no line is newer than. use %s to backport it", base, color.Yellow("gh cherry-pick"))
+		}
+		if target == defaultBranch {
+			logger.Successf("%s is the default branch", color.Cyan(target))
+			return nil
+		}
+
+		baseVersion, targetVersion := versionOf(base), versionOf(target)
+		if baseVersion == nil || targetVersion == nil {
+			logger.Warnf("could not tell whether %s is a newer line than %s", color.Cyan(target), color.Cyan(base))
+			return nil
+		}
+		if slices.Compare(targetVersion, baseVersion) <= 0 {
+			return fmt.Errorf("%s is not a newer line than %s. use %s to backport onto an older line", target, base, color.Yellow("gh cherry-pick"))
+		}
+
+		logger.Successf("%s is newer than %s", color.Cyan(target), color.Cyan(base))
+		return nil
+	})
+}
+
+// addForwardPortLine appends a line like "Forward-port of #123." to the
+// messages of the commits since the target branch.
+func (cherryPick *CherryPick) addForwardPortLine(ctx context.Context, desc description) error {
+	return tui.WithStep(ctx, "noting the forward-port", func(ctx context.Context, logger log.Logger) error {
+		line := fmt.Sprintf("Forward-port of %s.", desc.PRRef)
+		amend := fmt.Sprintf(`git commit --amend --quiet --allow-empty --no-verify -m "$(git log -1 --format=%%B)" -m '%s'`, line)
+		if err := NewCommand("git", "rebase", "--quiet", "--exec", amend, cherryPick.targetRef()).Run(ctx); err != nil {
+			if abortErr := abortOperation(context.WithoutCancel(ctx), logger); abortErr != nil {
+				logger.WithError(abortErr).Warnf("error aborting the rebase")
+			}
+			return fmt.Errorf("error noting the forward-port in the commit messages: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,27 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVersionOf(t *testing.T) {
+	testcases := []struct {
+		branch   string
+		expected []int
+	}{
+		{branch: "release/1.2", expected: []int{1, 2}},
+		{branch: "release-v10.0.3", expected: []int{10, 0, 3}},
+		{branch: "v1.2.x", expected: []int{1, 2}},
+		{branch: "team2/release/3.1", expected: []int{3, 1}},
+		{branch: "main", expected: nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.branch, func(t *testing.T) {
+			if actual := versionOf(tc.branch); !slices.Equal(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -21,3 +21,28 @@ func ghAPIQuery(ctx context.Context, hostname, endpoint, jqExpr string, headers
 	}
 	return strings.TrimSpace(stdout.String()), nil
 }
+
+// ghAPIQueryAll runs: gh api --hostname <hostname> --paginate <endpoint> --jq <jqExpr>
+// and returns trimmed stdout. jqExpr is applied to every page.
+func ghAPIQueryAll(ctx context.Context, hostname, endpoint, jqExpr string) (string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("gh", "api", "--hostname", hostname, "--paginate", endpoint, "--jq", jqExpr).Run(ctx, WithStdout(stdout)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// ghAPIRequest runs: gh api --hostname <hostname> --method <method> <endpoint> [-f key=value...]
+// and returns trimmed stdout.
+func ghAPIRequest(ctx context.Context, hostname, method, endpoint string, fields map[string]string) (string, error) {
+	args := []string{"api", "--hostname", hostname, "--method", method, endpoint}
+	for k, v := range fields {
+		args = append(args, "-f", k+"="+v)
+	}
+
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("gh", args...).Run(ctx, WithStdout(stdout)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -14,9 +16,10 @@ import (
 	"github.com/134130/gh-cherry-pick/internal/once"
 )
 
-var nameWithOwnerOnce = once.OnceValue[string]{}
-var repoWebURLOnce = once.OnceValue[string]{}
-var ghHostnameOnce = once.OnceValue[string]{}
+// These are remembered per directory set by CtxWithDir.
+var nameWithOwnerOnce = once.OnceValues[string, string]{}
+var repoWebURLOnce = once.OnceValues[string, string]{}
+var ghHostnameOnce = once.OnceValues[string, string]{}
 
 // GetGHHostname derives the GitHub hostname directly from the git remote URL,
 // supporting both HTTPS (https://ghe.example.com/owner/repo.git) and
@@ -24,7 +27,7 @@ var ghHostnameOnce = once.OnceValue[string]{}
 // This avoids the circular dependency of needing the gh CLI to determine
 // which hostname to pass to the gh CLI.
 func GetGHHostname(ctx context.Context) (string, error) {
-	return ghHostnameOnce.Do(ctx, func(ctx context.Context) (string, error) {
+	return ghHostnameOnce.Do(ctx, DirFromCtx(ctx), func(ctx context.Context) (string, error) {
 		remoteURL, err := GetRemoteURL(ctx)
 		if err != nil {
 			return "", err
@@ -47,7 +50,7 @@ func GetGHHostname(ctx context.Context) (string, error) {
 }
 
 func GetNameWithOwner(ctx context.Context) (string, error) {
-	return nameWithOwnerOnce.Do(ctx, func(ctx context.Context) (string, error) {
+	return nameWithOwnerOnce.Do(ctx, DirFromCtx(ctx), func(ctx context.Context) (string, error) {
 		stdout := &bytes.Buffer{}
 		args := []string{"repo", "view", "--json", "nameWithOwner", "--jq", ".nameWithOwner"}
 		if err := NewCommand("gh", args...).Run(ctx, WithStdout(stdout)); err != nil {
@@ -58,7 +61,7 @@ func GetNameWithOwner(ctx context.Context) (string, error) {
 }
 
 func GetRepoWebURL(ctx context.Context) (string, error) {
-	return repoWebURLOnce.Do(ctx, func(ctx context.Context) (string, error) {
+	return repoWebURLOnce.Do(ctx, DirFromCtx(ctx), func(ctx context.Context) (string, error) {
 		stdout := &bytes.Buffer{}
 		args := []string{"repo", "view", "--json", "url", "--jq", ".url"}
 		if err := NewCommand("gh", args...).Run(ctx, WithStdout(stdout)); err != nil {
@@ -68,6 +71,25 @@ func GetRepoWebURL(ctx context.Context) (string, error) {
 	})
 }
 
+// GetRepository returns the repository of the current directory, including its host.
+func GetRepository(ctx context.Context) (gitobj.Repository, error) {
+	hostname, err := GetGHHostname(ctx)
+	if err != nil {
+		return gitobj.Repository{}, fmt.Errorf("failed to get GH hostname: %w", err)
+	}
+
+	nameWithOwner, err := GetNameWithOwner(ctx)
+	if err != nil {
+		return gitobj.Repository{}, fmt.Errorf("failed to get repository name with owner: %w", err)
+	}
+
+	owner, name, ok := strings.Cut(nameWithOwner, "/")
+	if !ok {
+		return gitobj.Repository{}, fmt.Errorf("unexpected repository name with owner %q", nameWithOwner)
+	}
+	return gitobj.Repository{Host: hostname, Owner: owner, Name: name}, nil
+}
+
 func GetRepoRoot(ctx context.Context) (string, error) {
 	stdout := &bytes.Buffer{}
 	if err := NewCommand("git", "rev-parse", "--show-toplevel").Run(ctx, WithStdout(stdout)); err != nil {
@@ -76,9 +98,59 @@ func GetRepoRoot(ctx context.Context) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
-func GetPullRequest(ctx context.Context, number int) (*gitobj.PullRequest, error) {
+// GetGitDir returns the absolute path of the git directory.
+func GetGitDir(ctx context.Context) (string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "rev-parse", "--absolute-git-dir").Run(ctx, WithStdout(stdout)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// GetCurrentBranch returns the checked out branch, or an empty string when HEAD is detached.
+func GetCurrentBranch(ctx context.Context) (string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "branch", "--show-current").Run(ctx, WithStdout(stdout)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func RevParse(ctx context.Context, rev string) (string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "rev-parse", "--verify", "--end-of-options", rev).Run(ctx, WithStdout(stdout)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// GetConfig returns the value of a git config key, or an empty string when it is not set.
+func GetConfig(ctx context.Context, key string) string {
 	stdout := &bytes.Buffer{}
-	args := []string{"pr", "view", strconv.Itoa(number), "--json", "number,title,url,author,state,isDraft,mergeCommit,baseRefName,headRefName"}
+	if err := NewCommand("git", "config", "--get", key).Run(ctx, WithStdout(stdout)); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(stdout.String())
+}
+
+// GetConfigAll returns every value of a multi-valued config, e.g. one added
+// with git config --add.
+func GetConfigAll(ctx context.Context, key string) []string {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "config", "--get-all", key).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(stdout.String()), "\n")
+}
+
+func SetConfig(ctx context.Context, key, value string) error {
+	defer lockShared(ctx)()
+	return NewCommand("git", "config", key, value).Run(ctx)
+}
+
+func GetPullRequest(ctx context.Context, repo gitobj.Repository, number int) (*gitobj.PullRequest, error) {
+	stdout := &bytes.Buffer{}
+	args := []string{"pr", "view", strconv.Itoa(number), "--repo", repo.String(), "--json", "number,title,url,author,state,isDraft,mergeCommit,baseRefName,headRefName,commits"}
 	if err := NewCommand("gh", args...).Run(ctx, WithStdout(stdout)); err != nil {
 		return nil, fmt.Errorf("failed to get the pull request: %w", err)
 	}
@@ -90,6 +162,51 @@ func GetPullRequest(ctx context.Context, number int) (*gitobj.PullRequest, error
 	return &pr, nil
 }
 
+// GetPullRequestFields fills in more fields of the pull request, e.g.
+// "labels,milestone", which GetPullRequest does not fetch.
+func GetPullRequestFields(ctx context.Context, repo gitobj.Repository, pr *gitobj.PullRequest, fields string) error {
+	stdout := &bytes.Buffer{}
+	args := []string{"pr", "view", strconv.Itoa(pr.Number), "--repo", repo.String(), "--json", fields}
+	if err := NewCommand("gh", args...).Run(ctx, WithStdout(stdout)); err != nil {
+		return fmt.Errorf("failed to get the %s of the pull request: %w", fields, err)
+	}
+	if err := json.NewDecoder(stdout).Decode(pr); err != nil {
+		return fmt.Errorf("failed to unmarshal the pull request: %w", err)
+	}
+	return nil
+}
+
+// EditPullRequest runs gh pr edit with a flag for each value, e.g. --add-label.
+func EditPullRequest(ctx context.Context, prURL, flag string, values ...string) error {
+	args := []string{"pr", "edit", prURL}
+	for _, value := range values {
+		args = append(args, flag, value)
+	}
+	return NewCommand("gh", args...).Run(ctx)
+}
+
+// GetPullRequestNumberForCommit returns the number of the merged PR which
+// introduced the given commit, or 0 when there is none.
+func GetPullRequestNumberForCommit(ctx context.Context, repo gitobj.Repository, sha string) (int, error) {
+	numberStr, err := ghAPIQuery(ctx, repo.Host,
+		fmt.Sprintf("repos/%s/commits/%s/pulls", repo.NameWithOwner(), sha),
+		"map(select(.merged_at != null)) | .[0].number // empty",
+		map[string]string{"Accept": "application/vnd.github+json"},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get related PRs for commit %s: %w", sha, err)
+	}
+	if numberStr == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(numberStr)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected PR number %q for commit %s: %w", numberStr, sha, err)
+	}
+	return number, nil
+}
+
 func GetRemoteURL(ctx context.Context) (string, error) {
 	stdout := &bytes.Buffer{}
 	if err := NewCommand("git", "remote", "get-url", "origin").Run(ctx, WithStdout(stdout)); err != nil {
@@ -102,19 +219,187 @@ func Clone(ctx context.Context, remoteURL, targetDir string) error {
 	return NewCommand("git", "clone", remoteURL, targetDir).Run(ctx)
 }
 
+// AddWorktree checks out commitish, detached, in a new worktree at dir.
+func AddWorktree(ctx context.Context, dir, commitish string) error {
+	return NewCommand("git", "worktree", "add", "--detach", dir, commitish).Run(ctx)
+}
+
+// RemoveWorktree removes the worktree at dir, discarding its changes.
+func RemoveWorktree(ctx context.Context, dir string) error {
+	return NewCommand("git", "worktree", "remove", "--force", dir).Run(ctx)
+}
+
+// PruneWorktrees forgets the worktrees whose directory was deleted.
+func PruneWorktrees(ctx context.Context) error {
+	return NewCommand("git", "worktree", "prune").Run(ctx)
+}
+
+// CheckoutNewBranch creates newBranch tracking startPoint of remote. Tracking
+// writes the branch into the config, which the worktrees of a repository share.
 func CheckoutNewBranch(ctx context.Context, newBranch, remote, startPoint string) error {
+	defer lockShared(ctx)()
 	remoteStartPoint := fmt.Sprintf("%s/%s", remote, startPoint)
 	return NewCommand("git", "switch", "-c", newBranch, "--track", remoteStartPoint).Run(ctx)
 }
 
+// Switch checks out a branch, or detaches HEAD at a commit.
+func Switch(ctx context.Context, ref string) error {
+	if branchExists, _ := RevParse(ctx, "refs/heads/"+ref); branchExists != "" {
+		return NewCommand("git", "switch", ref).Run(ctx)
+	}
+	return NewCommand("git", "switch", "--detach", ref).Run(ctx)
+}
+
+// CheckBranchName checks that name is a valid branch name, which git cannot
+// take for an option or expand to another branch either, e.g. @{-1}.
+func CheckBranchName(ctx context.Context, name string) error {
+	if !strings.HasPrefix(name, "-") {
+		stdout := &bytes.Buffer{}
+		if err := NewCommand("git", "check-ref-format", "--branch", name).Run(ctx, WithStdout(stdout)); err == nil && strings.TrimSpace(stdout.String()) == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid branch name %q", name)
+}
+
+// DeleteBranch deletes branch, and its section of the config.
+func DeleteBranch(ctx context.Context, branch string) error {
+	defer lockShared(ctx)()
+	return NewCommand("git", "branch", "-D", branch).Run(ctx)
+}
+
+// Stash stashes the local changes including untracked files, and returns the
+// stash commit, or an empty string when there was nothing to stash.
+func Stash(ctx context.Context, message string) (string, error) {
+	// A stash of the user may be on top already.
+	before, _ := RevParse(ctx, "refs/stash")
+	if err := NewCommand("git", "stash", "push", "--include-untracked", "--message", message).Run(ctx); err != nil {
+		return "", err
+	}
+
+	after, err := RevParse(ctx, "refs/stash")
+	if err != nil || after == before {
+		return "", nil
+	}
+	return after, nil
+}
+
+// StashPop restores the stash commit returned by Stash and drops it from the stash list.
+func StashPop(ctx context.Context, stashRef string) error {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "stash", "list", "--format=%H").Run(ctx, WithStdout(stdout)); err != nil {
+		return err
+	}
+
+	for i, sha := range strings.Fields(stdout.String()) {
+		if sha == stashRef {
+			return NewCommand("git", "stash", "pop", fmt.Sprintf("stash@{%d}", i)).Run(ctx)
+		}
+	}
+	return fmt.Errorf("stash %s is not in the stash list", stashRef)
+}
+
+// Push pushes ref and sets it as the upstream, which writes into the config
+// and the refs of remote that the worktrees of a repository share.
 func Push(ctx context.Context, remote, ref string) error {
+	defer lockShared(ctx)()
 	return NewCommand("git", "push", "--set-upstream", remote, ref).Run(ctx)
 }
 
+// CreatePullRequest opens a pull request and returns its URL.
+func CreatePullRequest(ctx context.Context, repo gitobj.Repository, base, head, title, body string, draft bool) (string, error) {
+	stdout := &bytes.Buffer{}
+	args := []string{"pr", "create", "--repo", repo.String(), "--base", base, "--head", head, "--title", title, "--body", body}
+	if draft {
+		args = append(args, "--draft")
+	}
+	if err := NewCommand("gh", args...).Run(ctx, WithStdout(stdout)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// CommentOnPullRequest adds a comment to a pull request.
+func CommentOnPullRequest(ctx context.Context, repo gitobj.Repository, number int, body string) error {
+	return NewCommand("gh", "pr", "comment", strconv.Itoa(number), "--repo", repo.String(), "--body", body).Run(ctx)
+}
+
+// GetPermission returns the permission of a user on the repository: admin, write, read or none.
+func GetPermission(ctx context.Context, repo gitobj.Repository, user string) (string, error) {
+	return ghAPIQuery(ctx, repo.Host,
+		fmt.Sprintf("repos/%s/collaborators/%s/permission", repo.NameWithOwner(), user),
+		".permission",
+		nil,
+	)
+}
+
+// AddReaction reacts to an issue or PR comment, e.g. with "eyes" or "rocket".
+func AddReaction(ctx context.Context, repo gitobj.Repository, commentID int64, content string) error {
+	_, err := ghAPIRequest(ctx, repo.Host, "POST",
+		fmt.Sprintf("repos/%s/issues/comments/%d/reactions", repo.NameWithOwner(), commentID),
+		map[string]string{"content": content},
+	)
+	return err
+}
+
+// ConflictedFiles returns the paths which have unresolved conflicts.
+func ConflictedFiles(ctx context.Context) ([]string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "diff", "--name-only", "--diff-filter=U", "-z").Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range strings.Split(stdout.String(), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
 func Fetch(ctx context.Context, remote, refspec string) error {
+	defer lockShared(ctx)()
 	return NewCommand("git", "fetch", "--recurse-submodules", remote, refspec).Run(ctx)
 }
 
+// MergeCommits returns those of commits which are merge commits.
+func MergeCommits(ctx context.Context, commits ...string) ([]string, error) {
+	stdout := &bytes.Buffer{}
+	args := append([]string{"rev-list", "--no-walk", "--min-parents=2"}, commits...)
+	if err := NewCommand("git", args...).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, err
+	}
+	return strings.Fields(stdout.String()), nil
+}
+
+// MergeBase returns the best common ancestor of a and b.
+func MergeBase(ctx context.Context, a, b string) (string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "merge-base", a, b).Run(ctx, WithStdout(stdout)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// RevList returns the commits of the given revision range, oldest first.
+func RevList(ctx context.Context, revisionRange string) ([]string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "rev-list", "--reverse", revisionRange).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, err
+	}
+	return strings.Fields(stdout.String()), nil
+}
+
+// RevListSubjects returns the subjects of the commits of the given revision range.
+func RevListSubjects(ctx context.Context, revisionRange string) ([]string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "log", "--format=%s", revisionRange).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSpace(stdout.String()), "\n"), nil
+}
+
 func IsDirty(ctx context.Context) (bool, error) {
 	stdout := &bytes.Buffer{}
 	if err := NewCommand("git", "status", "--porcelain").Run(ctx, WithStdout(stdout)); err != nil {
@@ -123,15 +408,26 @@ func IsDirty(ctx context.Context) (bool, error) {
 	return len(strings.TrimSpace(stdout.String())) > 0, nil
 }
 
+// HasDiff reports whether the trees of two commits differ.
+func HasDiff(ctx context.Context, from, to string) (bool, error) {
+	err := NewCommand("git", "diff", "--quiet", from, to, "--").Run(ctx)
+	var gitError *GitError
+	if errors.As(err, &gitError) && gitError.ExitCode == 1 {
+		return true, nil
+	}
+	return false, err
+}
+
 func IsInRebaseOrAm(ctx context.Context) (bool, error) {
-	repoRoot, err := GetRepoRoot(ctx)
+	// The git directory is not <root>/.git in a worktree.
+	gitDir, err := GetGitDir(ctx)
 	if err != nil {
-		return false, fmt.Errorf("failed to get the repository root: %w", err)
+		return false, fmt.Errorf("failed to get the git directory: %w", err)
 	}
 
 	for _, magicFile := range []string{
-		fmt.Sprintf("%s/.git/rebase-apply", repoRoot),
-		fmt.Sprintf("%s/.git/rebase-merge", repoRoot),
+		filepath.Join(gitDir, "rebase-apply"),
+		filepath.Join(gitDir, "rebase-merge"),
 	} {
 		if _, err = os.Stat(magicFile); err == nil {
 			return true, nil
@@ -142,3 +438,48 @@ func IsInRebaseOrAm(ctx context.Context) (bool, error) {
 
 	return false, nil
 }
+
+// IsInAm reports whether a git am stopped, unlike a git rebase, which
+// IsInRebaseOrAm reports too.
+func IsInAm(ctx context.Context) (bool, error) {
+	gitDir, err := GetGitDir(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the git directory: %w", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(gitDir, "rebase-apply", "applying")); err == nil {
+		return true, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+// IsInRevert reports whether a git revert stopped on conflicts.
+func IsInRevert(ctx context.Context) (bool, error) {
+	gitDir, err := GetGitDir(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the git directory: %w", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(gitDir, "REVERT_HEAD")); err == nil {
+		return true, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+func IsInCherryPick(ctx context.Context) (bool, error) {
+	gitDir, err := GetGitDir(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the git directory: %w", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(gitDir, "CHERRY_PICK_HEAD")); err == nil {
+		return true, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+	return false, nil
+}
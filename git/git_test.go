@@ -0,0 +1,63 @@
+package git
+
+import "testing"
+
+func TestStash(t *testing.T) {
+	testcases := []struct {
+		name string
+		// change leaves local changes to stash, if any.
+		change    string
+		wantStash bool
+	}{{
+		name:      "local changes",
+		change:    "echo x > x.txt",
+		wantStash: true,
+	}, {
+		name:   "nothing to stash",
+		change: "true",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, sh := newTestRepo(t)
+			// A stash of the user, which must be left alone.
+			userStash := sh("echo user > user.txt && git stash push -qu && git rev-parse refs/stash")
+			sh(tc.change)
+
+			stashRef, err := Stash(ctx, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantStash && (stashRef == "" || stashRef == userStash) {
+				t.Errorf("expected a new stash, got %q", stashRef)
+			} else if !tc.wantStash && stashRef != "" {
+				t.Errorf("expected no stash, got %q", stashRef)
+			}
+		})
+	}
+}
+
+func TestCheckBranchName(t *testing.T) {
+	testcases := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "release/1.2"},
+		{name: "--upload-pack=touch x", wantErr: true},
+		{name: "-b", wantErr: true},
+		{name: "release..1.2", wantErr: true},
+		{name: "release 1.2", wantErr: true},
+		{name: "@{-1}", wantErr: true},
+	}
+
+	ctx, sh := newTestRepo(t)
+	// @{-1} expands to the branch checked out before.
+	sh("git checkout -q release && git checkout -q main")
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := CheckBranchName(ctx, tc.name); (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
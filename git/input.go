@@ -0,0 +1,97 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+// Input is what the user asked to cherry-pick. Exactly one of PRNumber,
+// CommitSHA and CommitRange is set.
+type Input struct {
+	// Repo is the repository the PR or commit belongs to. It is nil when the
+	// input does not name a repository, meaning the current one.
+	Repo        *gitobj.Repository
+	PRNumber    int
+	CommitSHA   string
+	CommitRange string
+}
+
+var (
+	nameWithOwnerPRPattern = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+	commitSHAPattern       = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+)
+
+// ParseInput parses the value of the -pr flag. It accepts:
+//   - a PR number, optionally prefixed with '#'
+//   - a PR URL such as https://github.com/owner/repo/pull/123
+//   - a PR reference such as owner/repo#123
+//   - a merge commit SHA, which is later resolved to its PR
+//   - a commit range such as abc1234..def5678, for changes without a PR
+//
+// A value made of digits only is always treated as a PR number.
+func ParseInput(s string) (Input, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Input{}, fmt.Errorf("empty input")
+	}
+
+	if number, err := strconv.Atoi(strings.TrimPrefix(s, "#")); err == nil {
+		if number <= 0 {
+			return Input{}, fmt.Errorf("invalid PR number %q", s)
+		}
+		return Input{PRNumber: number}, nil
+	}
+
+	if strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://") {
+		return parsePRURL(s)
+	}
+
+	if m := nameWithOwnerPRPattern.FindStringSubmatch(s); m != nil {
+		number, _ := strconv.Atoi(m[3])
+		return Input{
+			Repo:     &gitobj.Repository{Owner: m[1], Name: m[2]},
+			PRNumber: number,
+		}, nil
+	}
+
+	if from, to, ok := strings.Cut(s, ".."); ok {
+		if from == "" || to == "" || strings.HasPrefix(to, ".") {
+			return Input{}, fmt.Errorf("invalid commit range %q: expected FROM..TO", s)
+		}
+		return Input{CommitRange: s}, nil
+	}
+
+	if commitSHAPattern.MatchString(s) {
+		return Input{CommitSHA: strings.ToLower(s)}, nil
+	}
+
+	return Input{}, fmt.Errorf("invalid input %q: expected a PR number, PR URL, owner/repo#number, commit SHA or commit range", s)
+}
+
+func parsePRURL(s string) (Input, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Input{}, fmt.Errorf("cannot parse PR URL %q: %w", s, err)
+	}
+
+	// /owner/repo/pull/123[/files|/commits|...]
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[2] != "pull" {
+		return Input{}, fmt.Errorf("invalid PR URL %q: expected https://HOST/OWNER/REPO/pull/NUMBER", s)
+	}
+
+	number, err := strconv.Atoi(parts[3])
+	if err != nil || number <= 0 {
+		return Input{}, fmt.Errorf("invalid PR URL %q: invalid PR number %q", s, parts[3])
+	}
+
+	return Input{
+		Repo:     &gitobj.Repository{Host: u.Host, Owner: parts[0], Name: parts[1]},
+		PRNumber: number,
+	}, nil
+}
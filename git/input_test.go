@@ -0,0 +1,82 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+func TestParseInput(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected Input
+		error    bool
+	}{{
+		name:     "PR number",
+		input:    "123",
+		expected: Input{PRNumber: 123},
+	}, {
+		name:     "PR number with hash",
+		input:    "#123",
+		expected: Input{PRNumber: 123},
+	}, {
+		name:  "PR URL",
+		input: "https://ghe.example.com/owner/repo/pull/123/files",
+		expected: Input{
+			Repo:     &gitobj.Repository{Host: "ghe.example.com", Owner: "owner", Name: "repo"},
+			PRNumber: 123,
+		},
+	}, {
+		name:  "owner/repo#number",
+		input: "owner/my.repo#7",
+		expected: Input{
+			Repo:     &gitobj.Repository{Owner: "owner", Name: "my.repo"},
+			PRNumber: 7,
+		},
+	}, {
+		name:     "merge commit SHA",
+		input:    "ABCDEF1234",
+		expected: Input{CommitSHA: "abcdef1234"},
+	}, {
+		name:     "commit range",
+		input:    "abc1234..def5678",
+		expected: Input{CommitRange: "abc1234..def5678"},
+	}, {
+		name:  "symmetric difference",
+		input: "abc1234...def5678",
+		error: true,
+	}, {
+		name:  "issue URL",
+		input: "https://github.com/owner/repo/issues/123",
+		error: true,
+	}, {
+		name:  "zero",
+		input: "0",
+		error: true,
+	}, {
+		name:  "branch name",
+		input: "feature/foo",
+		error: true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			input, err := ParseInput(tc.input)
+			if tc.error {
+				if err == nil {
+					t.Errorf("expected error, got %+v", input)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(input, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, input)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package git
+
+import (
+	"context"
+	"sync"
+)
+
+type sharedLockKey struct{}
+
+// CtxWithSharedLock makes the cherry-picks running at the same time in one
+// repository, in worktrees of it, take turns with mu updating what they
+// share: the refs of origin, the config of their branches, and the comment
+// on the PR.
+func CtxWithSharedLock(ctx context.Context, mu *sync.Mutex) context.Context {
+	return context.WithValue(ctx, sharedLockKey{}, mu)
+}
+
+// lockShared takes the shared lock of ctx, if any, and returns its unlock.
+func lockShared(ctx context.Context) func() {
+	mu, ok := ctx.Value(sharedLockKey{}).(*sync.Mutex)
+	if !ok {
+		return func() {}
+	}
+	mu.Lock()
+	return mu.Unlock
+}
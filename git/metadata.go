@@ -0,0 +1,137 @@
+package git
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// MetadataOptions selects what a cherry-pick PR gets from the original PR.
+type MetadataOptions struct {
+	// Copy copies the labels, milestone, assignees, reviewers and projects.
+	Copy bool
+	// DropLabels are globs of the labels not to copy, e.g. "backport *".
+	DropLabels []string
+	// AddLabels are added to the labels.
+	AddLabels []string
+	// AssignAuthor assigns the author of the original PR.
+	AssignAuthor bool
+}
+
+func (o MetadataOptions) isEmpty() bool {
+	return !o.Copy && !o.AssignAuthor && len(o.AddLabels) == 0
+}
+
+// metadata is what is set on a cherry-pick PR.
+type metadata struct {
+	Labels    []string
+	Assignees []string
+	Reviewers []string
+	Milestone string
+	Projects  []string
+}
+
+// metadataOf returns the metadata of the cherry-pick PR of pr, which is nil
+// for commit ranges.
+func (o MetadataOptions) metadataOf(pr *gitobj.PullRequest) metadata {
+	var m metadata
+	if pr != nil && o.Copy {
+		for _, label := range pr.Labels {
+			if !slices.ContainsFunc(o.DropLabels, func(glob string) bool { return matchGlob(glob, label.Name) }) {
+				m.Labels = appendUnique(m.Labels, label.Name)
+			}
+		}
+		for _, assignee := range pr.Assignees {
+			m.Assignees = appendUnique(m.Assignees, assignee.Login)
+		}
+		for _, request := range pr.ReviewRequests {
+			m.Reviewers = appendUnique(m.Reviewers, request.Reviewer())
+		}
+		for _, review := range pr.LatestReviews {
+			// The author of the cherry-pick PR cannot be requested, which is
+			// usually the author of the original PR too when run by hand.
+			if review.Author.Login != pr.Author.Login {
+				m.Reviewers = appendUnique(m.Reviewers, review.Author.Login)
+			}
+		}
+		if pr.Milestone != nil {
+			m.Milestone = pr.Milestone.Title
+		}
+		for _, item := range pr.ProjectItems {
+			m.Projects = appendUnique(m.Projects, item.Title)
+		}
+	}
+
+	for _, label := range o.AddLabels {
+		m.Labels = appendUnique(m.Labels, label)
+	}
+	if pr != nil && o.AssignAuthor && isUser(pr.Author.Login) {
+		m.Assignees = appendUnique(m.Assignees, pr.Author.Login)
+	}
+	return m
+}
+
+// applyMetadata sets the metadata on the cherry-pick PR. Each kind is set on
+// its own, so that e.g. a missing label does not lose the reviewers; failures
+// are only logged, as the PR exists already.
+func applyMetadata(ctx context.Context, logger log.Logger, prURL string, m metadata) {
+	var milestone []string
+	if m.Milestone != "" {
+		milestone = []string{m.Milestone}
+	}
+
+	edits := []struct {
+		name   string
+		flag   string
+		values []string
+	}{
+		{name: "labels", flag: "--add-label", values: m.Labels},
+		{name: "assignees", flag: "--add-assignee", values: m.Assignees},
+		{name: "milestone", flag: "--milestone", values: milestone},
+		{name: "projects", flag: "--add-project", values: m.Projects},
+	}
+
+	for _, edit := range edits {
+		if len(edit.values) == 0 {
+			continue
+		}
+		logger.WithField(edit.name, strings.Join(edit.values, ", ")).Infof("setting %s", edit.name)
+		if err := EditPullRequest(ctx, prURL, edit.flag, edit.values...); err != nil {
+			logger.WithError(err).Warnf("error setting %s", edit.name)
+		}
+	}
+
+	// Reviewers are requested one by one, as requesting the PR author fails.
+	for _, reviewer := range m.Reviewers {
+		logger.WithField("reviewer", reviewer).Infof("requesting review")
+		if err := EditPullRequest(ctx, prURL, "--add-reviewer", reviewer); err != nil {
+			logger.WithError(err).Warnf("error requesting review from %s", reviewer)
+		}
+	}
+}
+
+// matchGlob matches s against a glob in which * matches any string, including
+// slashes, and ? any character.
+func matchGlob(glob, s string) bool {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	matched, _ := regexp.MatchString("^"+pattern+"$", s)
+	return matched
+}
+
+// isUser reports whether login is a user which can be assigned, unlike apps.
+func isUser(login string) bool {
+	return login != "" && !strings.HasSuffix(login, "[bot]") && !strings.HasPrefix(login, "app/")
+}
+
+func appendUnique(items []string, item string) []string {
+	if item == "" || slices.Contains(items, item) {
+		return items
+	}
+	return append(items, item)
+}
@@ -0,0 +1,55 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+func TestMetadataOf(t *testing.T) {
+	pr := &gitobj.PullRequest{
+		Labels:         []gitobj.Label{{Name: "bug"}, {Name: "backport release/1.2"}, {Name: "area/api"}},
+		Assignees:      []gitobj.User{{Login: "alice"}},
+		ReviewRequests: []gitobj.ReviewRequest{{Login: "bob"}},
+		LatestReviews:  []gitobj.Review{{Author: gitobj.User{Login: "carol"}}, {Author: gitobj.User{Login: "octocat"}}},
+		Milestone:      &gitobj.Milestone{Number: 3, Title: "v1.2.1"},
+		ProjectItems:   []gitobj.ProjectItem{{Title: "Roadmap"}},
+	}
+	pr.Author.Login = "octocat"
+
+	testcases := []struct {
+		name     string
+		options  MetadataOptions
+		pr       *gitobj.PullRequest
+		expected metadata
+	}{{
+		name:    "copy with filters",
+		options: MetadataOptions{Copy: true, DropLabels: []string{"backport *"}, AddLabels: []string{"backport"}, AssignAuthor: true},
+		pr:      pr,
+		expected: metadata{
+			Labels:    []string{"bug", "area/api", "backport"},
+			Assignees: []string{"alice", "octocat"},
+			Reviewers: []string{"bob", "carol"},
+			Milestone: "v1.2.1",
+			Projects:  []string{"Roadmap"},
+		},
+	}, {
+		name:     "assign author only",
+		options:  MetadataOptions{AssignAuthor: true},
+		pr:       pr,
+		expected: metadata{Assignees: []string{"octocat"}},
+	}, {
+		name:     "commit range",
+		options:  MetadataOptions{Copy: true, AddLabels: []string{"backport"}, AssignAuthor: true},
+		expected: metadata{Labels: []string{"backport"}},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if m := tc.options.metadataOf(tc.pr); !reflect.DeepEqual(m, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, m)
+			}
+		})
+	}
+}
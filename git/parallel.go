@@ -0,0 +1,171 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+// Parallel cherry-picks onto several targets at the same time, each in its
+// own temporary worktree of the repository, so that they do not share a
+// checkout. The worktree of a cherry-pick which stops on conflicts is kept
+// for resolving them.
+type Parallel struct {
+	// CherryPick is the cherry-pick onto each target, whose OnTo is set per target.
+	CherryPick CherryPick
+	Targets    []string
+	// Jobs is how many cherry-picks run at the same time.
+	Jobs int
+
+	// Results are filled in while running, in the order of Targets.
+	Results []ParallelResult
+}
+
+// ParallelResult is the outcome of the cherry-pick onto a target of a Parallel.
+type ParallelResult struct {
+	Target string
+	Result Result
+	Err    error
+	// Log is the output of the cherry-pick.
+	Log string
+	// Worktree is the worktree kept for resolving the conflicts, if any.
+	Worktree string
+}
+
+func (parallel *Parallel) RunWithContext(ctx context.Context) error {
+	logger := log.LoggerFromCtx(ctx)
+	logger.Infof("🍒 cherry-picking onto %d branches, %d at a time\n", len(parallel.Targets), parallel.Jobs)
+
+	template := parallel.CherryPick
+	if template.Worktree {
+		cacheDir, err := worktreeCache(ctx)
+		if err != nil {
+			return err
+		}
+		ctx = CtxWithDir(ctx, cacheDir)
+		// Each target gets a worktree of the cache instead, and the branches
+		// are pushed as they would be from the cache.
+		template.Worktree, template.Push = false, true
+	}
+
+	worktrees := make([]string, len(parallel.Targets))
+	err := tui.WithStep(ctx, "creating the worktrees", func(ctx context.Context, logger log.Logger) error {
+		for i, target := range parallel.Targets {
+			dir, err := os.MkdirTemp("", "gh-cherry-pick-"+strings.ReplaceAll(target, "/", "-")+"-")
+			if err != nil {
+				return fmt.Errorf("error creating the worktree directory of %s: %w", target, err)
+			}
+
+			logger.WithField("worktree", dir).Infof("creating the worktree of %s", color.Cyan(target))
+			if err = AddWorktree(ctx, dir, "HEAD"); err != nil {
+				_ = os.RemoveAll(dir)
+				return fmt.Errorf("error creating the worktree of %s: %w", target, err)
+			}
+			worktrees[i] = dir
+		}
+		return nil
+	})
+	if err != nil {
+		parallel.removeWorktrees(ctx, worktrees)
+		return err
+	}
+
+	titles := make([]string, len(parallel.Targets))
+	for i, target := range parallel.Targets {
+		titles[i] = "cherry-picking onto " + color.Cyan(target)
+	}
+
+	parallel.Results = make([]ParallelResult, len(parallel.Targets))
+	_ = tui.WithStep(CtxWithSharedLock(ctx, &sync.Mutex{}), "cherry-picking", func(ctx context.Context, logger log.Logger) error {
+		tui.WithSpinners(ctx, titles, parallel.Jobs, func(ctx context.Context, i int) error {
+			r := &parallel.Results[i]
+			r.Target = parallel.Targets[i]
+
+			var output bytes.Buffer
+			cherryPick := template
+			cherryPick.OnTo = r.Target
+			r.Err = cherryPick.RunWithContext(CtxWithDir(log.CtxWithLoggerTo(ctx, &output), worktrees[i]))
+			r.Result, r.Log = cherryPick.Result, output.String()
+
+			var conflictErr *ConflictError
+			if errors.As(r.Err, &conflictErr) || (r.Err != nil && cherryPick.KeepOnFailure) {
+				r.Worktree, worktrees[i] = worktrees[i], ""
+			}
+			return r.Err
+		})
+		return nil
+	})
+
+	parallel.removeWorktrees(ctx, worktrees)
+	return parallel.report(ctx)
+}
+
+// removeWorktrees removes the worktrees which are not kept, skipping the
+// empty ones.
+func (parallel *Parallel) removeWorktrees(ctx context.Context, worktrees []string) {
+	logger := log.LoggerFromCtx(ctx)
+	// The worktrees are removed even when interrupted.
+	ctx = context.WithoutCancel(ctx)
+	for _, dir := range worktrees {
+		if dir == "" {
+			continue
+		}
+		if err := RemoveWorktree(ctx, dir); err != nil {
+			logger.WithError(err).WithField("worktree", dir).Warnf("error removing the worktree")
+		}
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// report prints the output of each cherry-pick and its outcome, in the
+// order of the targets, and fails when any of them failed.
+func (parallel *Parallel) report(ctx context.Context) error {
+	logger, w := log.LoggerFromCtx(ctx), log.WriterFromCtx(ctx)
+	for _, r := range parallel.Results {
+		logger.Infof("🍒 %s\n", color.Bold("onto "+r.Target))
+		_, _ = fmt.Fprint(w, r.Log)
+		if r.Err != nil {
+			logger.Failf(r.Err.Error())
+			_, _ = fmt.Fprintln(w)
+		}
+	}
+
+	logger.Infof("🍒 %s", color.Bold("report\n"))
+	failed := 0
+	for _, r := range parallel.Results {
+		name := color.Cyan(r.Target)
+		switch {
+		case r.Worktree != "":
+			failed++
+			message := "failed"
+			var conflictErr *ConflictError
+			if errors.As(r.Err, &conflictErr) {
+				message = "conflicts"
+			}
+			logger.Failf("%s: %s, kept in the worktree %s\n    run %s when done with it", name, message, color.Cyan(r.Worktree), color.Yellow(fmt.Sprintf("`git worktree remove %s`", r.Worktree)))
+		case r.Err != nil:
+			failed++
+			message, _, _ := strings.Cut(r.Err.Error(), "\n")
+			logger.Failf("%s: %s", name, message)
+		case r.Result.AlreadyPresent:
+			logger.Successf("%s: already present", name)
+		case r.Result.PullRequestURL != "":
+			logger.Successf("%s: %s", name, r.Result.PullRequestURL)
+		default:
+			logger.Successf("%s: %s", name, r.Result.Branch)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cherry-pick(s) failed", failed, len(parallel.Results))
+	}
+	return nil
+}
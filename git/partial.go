@@ -0,0 +1,191 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+// CommitSelection selects commits of a PR, either by SHA prefix or by
+// 1-based index in the PR, e.g. "1,3-4,abc1234".
+type CommitSelection []string
+
+func ParseCommitSelection(s string) (CommitSelection, error) {
+	var selection CommitSelection
+	for _, item := range SplitList(s) {
+		if commitSHAPattern.MatchString(item) {
+			selection = append(selection, strings.ToLower(item))
+			continue
+		}
+		if _, _, err := parseIndexRange(item); err != nil {
+			return nil, fmt.Errorf("invalid commit selection %q: %w", item, err)
+		}
+		selection = append(selection, item)
+	}
+	return selection, nil
+}
+
+// Select returns the selected commits in the order they appear in commits.
+func (s CommitSelection) Select(commits []gitobj.Commit) ([]gitobj.Commit, error) {
+	selected := make([]bool, len(commits))
+	for _, item := range s {
+		if commitSHAPattern.MatchString(item) {
+			i := slices.IndexFunc(commits, func(c gitobj.Commit) bool { return strings.HasPrefix(c.Oid, item) })
+			if i < 0 {
+				return nil, fmt.Errorf("commit %s is not part of the PR", item)
+			}
+			selected[i] = true
+			continue
+		}
+
+		from, to, err := parseIndexRange(item)
+		if err != nil {
+			return nil, err
+		}
+		if to > len(commits) {
+			return nil, fmt.Errorf("commit index %s is out of range: the PR has %d commit(s)", item, len(commits))
+		}
+		for i := from; i <= to; i++ {
+			selected[i-1] = true
+		}
+	}
+
+	var result []gitobj.Commit
+	for i, commit := range commits {
+		if selected[i] {
+			result = append(result, commit)
+		}
+	}
+	return result, nil
+}
+
+func (s CommitSelection) String() string {
+	return strings.Join(s, ",")
+}
+
+func parseIndexRange(s string) (int, int, error) {
+	fromStr, toStr, isRange := strings.Cut(s, "-")
+	from, err := strconv.Atoi(fromStr)
+	if err != nil || from < 1 {
+		return 0, 0, fmt.Errorf("expected a commit SHA, an index or an index range like 2-4")
+	}
+	if !isRange {
+		return from, from, nil
+	}
+
+	to, err := strconv.Atoi(toStr)
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("expected a commit SHA, an index or an index range like 2-4")
+	}
+	return from, to, nil
+}
+
+// PathFilter restricts the files applied by a cherry-pick with glob patterns.
+type PathFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// ParsePathFilter parses comma-separated include and exclude globs.
+func ParsePathFilter(include, exclude string) PathFilter {
+	return PathFilter{Include: SplitList(include), Exclude: SplitList(exclude)}
+}
+
+func (f PathFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+func (f PathFilter) pathspecs() []string {
+	pathspecs := make([]string, 0, len(f.Include)+len(f.Exclude))
+	for _, pattern := range f.Include {
+		pathspecs = append(pathspecs, ":(glob)"+pattern)
+	}
+	for _, pattern := range f.Exclude {
+		pathspecs = append(pathspecs, ":(glob,exclude)"+pattern)
+	}
+	return pathspecs
+}
+
+// partialNote describes what part of ref was cherry-picked, or forward-ported
+// as kind tells. It is added to the messages of the cherry-picked commits and
+// to the PR body.
+func partialNote(kind, ref string, selection CommitSelection, filter PathFilter) string {
+	lines := []string{fmt.Sprintf("Partial %s of %s.", kind, ref)}
+	if len(selection) > 0 {
+		lines = append(lines, fmt.Sprintf("Commits: %s", selection))
+	}
+	if len(filter.Include) > 0 {
+		lines = append(lines, fmt.Sprintf("Included paths: %s", strings.Join(filter.Include, ", ")))
+	}
+	if len(filter.Exclude) > 0 {
+		lines = append(lines, fmt.Sprintf("Excluded paths: %s", strings.Join(filter.Exclude, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatPatches returns the given commits as a mailbox limited to the paths
+// of filter, with note appended to each commit message.
+func formatPatches(ctx context.Context, commits []string, filter PathFilter, note string) (*bytes.Buffer, error) {
+	var mbox bytes.Buffer
+	for _, commit := range commits {
+		args := []string{"format-patch", "--stdout", "--no-signature", commit + "^.." + commit}
+		if pathspecs := filter.pathspecs(); len(pathspecs) > 0 {
+			args = append(append(args, "--"), pathspecs...)
+		}
+		if err := NewCommand("git", args...).Run(ctx, WithStdout(&mbox)); err != nil {
+			return nil, fmt.Errorf("error formatting commit %s: %w", commit, err)
+		}
+	}
+
+	if note == "" {
+		return &mbox, nil
+	}
+	return bytes.NewBufferString(addNoteToPatches(mbox.String(), note)), nil
+}
+
+var patchSeparatorPattern = regexp.MustCompile(`^From [0-9a-f]{40} Mon Sep 17 00:00:00 2001$`)
+
+// addNoteToPatches appends note as the last paragraph of each commit message
+// in a mailbox produced by git format-patch.
+func addNoteToPatches(mbox, note string) string {
+	var sb strings.Builder
+
+	inHeader, noted := false, true
+	previous := ""
+	for _, line := range strings.SplitAfter(mbox, "\n") {
+		trimmed := strings.TrimSuffix(line, "\n")
+		switch {
+		case patchSeparatorPattern.MatchString(trimmed):
+			inHeader, noted = true, false
+		case inHeader && trimmed == "":
+			inHeader = false
+		case !inHeader && !noted && trimmed == "---":
+			if previous != "" {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(note + "\n")
+			noted = true
+		}
+		sb.WriteString(line)
+		previous = trimmed
+	}
+
+	return sb.String()
+}
+
+// SplitList splits a comma-separated flag value, dropping empty items.
+func SplitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
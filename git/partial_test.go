@@ -0,0 +1,159 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+func TestCommitSelectionSelect(t *testing.T) {
+	commits := []gitobj.Commit{
+		{Oid: "1111111111111111111111111111111111111111"},
+		{Oid: "2222222222222222222222222222222222222222"},
+		{Oid: "3333333333333333333333333333333333333333"},
+		{Oid: "4444444444444444444444444444444444444444"},
+	}
+
+	testcases := []struct {
+		name     string
+		input    string
+		expected []string
+		error    bool
+	}{{
+		name:     "index",
+		input:    "2",
+		expected: []string{"2222222222222222222222222222222222222222"},
+	}, {
+		name:     "index range and SHA keep PR order",
+		input:    "4444444,1-2",
+		expected: []string{"1111111111111111111111111111111111111111", "2222222222222222222222222222222222222222", "4444444444444444444444444444444444444444"},
+	}, {
+		name:  "index out of range",
+		input: "3-5",
+		error: true,
+	}, {
+		name:  "unknown SHA",
+		input: "5555555",
+		error: true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			selection, err := ParseCommitSelection(tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			selected, err := selection.Select(commits)
+			if tc.error {
+				if err == nil {
+					t.Errorf("expected error, got %v", selected)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var oids []string
+			for _, commit := range selected {
+				oids = append(oids, commit.Oid)
+			}
+			if !reflect.DeepEqual(oids, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, oids)
+			}
+		})
+	}
+}
+
+func TestAddNoteToPatches(t *testing.T) {
+	const from = "From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001\n"
+	const from2 = "From 2222222222222222222222222222222222222222 Mon Sep 17 00:00:00 2001\n"
+	const diff = " a.txt | 1 +\n\ndiff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1,2 @@\n 1\n+2\n"
+
+	testcases := []struct {
+		name     string
+		mbox     string
+		expected string
+	}{{
+		name:     "subject only",
+		mbox:     from + "Subject: [PATCH] Fix\n\n---\n" + diff,
+		expected: from + "Subject: [PATCH] Fix\n\nPartial.\n---\n" + diff,
+	}, {
+		name:     "body",
+		mbox:     from + "Subject: [PATCH] Fix\n\nBecause.\n---\n" + diff,
+		expected: from + "Subject: [PATCH] Fix\n\nBecause.\n\nPartial.\n---\n" + diff,
+	}, {
+		name: "multiple patches",
+		mbox: from + "Subject: [PATCH 1/2] Fix\n\nBecause.\n---\n" + diff +
+			from2 + "Subject: [PATCH 2/2] Test\n\n---\n" + diff,
+		expected: from + "Subject: [PATCH 1/2] Fix\n\nBecause.\n\nPartial.\n---\n" + diff +
+			from2 + "Subject: [PATCH 2/2] Test\n\nPartial.\n---\n" + diff,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := addNoteToPatches(tc.mbox, "Partial."); actual != tc.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPathFilterPathspecs(t *testing.T) {
+	testcases := []struct {
+		name     string
+		include  string
+		exclude  string
+		expected []string
+	}{{
+		name:     "empty",
+		expected: []string{},
+	}, {
+		name:     "include",
+		include:  "src/**, docs/*.md",
+		expected: []string{":(glob)src/**", ":(glob)docs/*.md"},
+	}, {
+		name:     "include and exclude",
+		include:  "src/**",
+		exclude:  "src/**/*_test.go",
+		expected: []string{":(glob)src/**", ":(glob,exclude)src/**/*_test.go"},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := ParsePathFilter(tc.include, tc.exclude).pathspecs(); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPartialNote(t *testing.T) {
+	testcases := []struct {
+		name      string
+		kind      string
+		selection CommitSelection
+		filter    PathFilter
+		expected  string
+	}{{
+		name:      "commits",
+		kind:      "cherry-pick",
+		selection: CommitSelection{"1", "3-4"},
+		expected:  "Partial cherry-pick of #12.\nCommits: 1,3-4",
+	}, {
+		name:     "paths",
+		kind:     "forward-port",
+		filter:   PathFilter{Include: []string{"src/**", "docs/*"}, Exclude: []string{"*_test.go"}},
+		expected: "Partial forward-port of #12.\nIncluded paths: src/**, docs/*\nExcluded paths: *_test.go",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := partialNote(tc.kind, "#12", tc.selection, tc.filter); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
 )
 
 type MergeStrategy string
@@ -25,9 +27,9 @@ func (m MergeStrategy) Validate() error {
 	}
 }
 
-func PRMergedWith(ctx context.Context, prNumber int) (MergeStrategy, error) {
+func PRMergedWith(ctx context.Context, repo gitobj.Repository, prNumber int) (MergeStrategy, error) {
 	stdout := &bytes.Buffer{}
-	args := []string{"pr", "view", strconv.Itoa(prNumber), "--json", "mergeCommit", "--jq", ".mergeCommit.oid"}
+	args := []string{"pr", "view", strconv.Itoa(prNumber), "--repo", repo.String(), "--json", "mergeCommit", "--jq", ".mergeCommit.oid"}
 	if err := NewCommand("gh", args...).Run(ctx, WithStdout(stdout)); err != nil {
 		return "", fmt.Errorf("failed to get merge commit SHA for PR #%d: %w", prNumber, err)
 	}
@@ -37,22 +39,12 @@ func PRMergedWith(ctx context.Context, prNumber int) (MergeStrategy, error) {
 		return "", fmt.Errorf("failed to get merge commit SHA for PR #%d: PR not merged", prNumber)
 	}
 
-	return inspectMergeStrategy(ctx, prNumber, mergeCommitSHA)
+	return inspectMergeStrategy(ctx, repo, prNumber, mergeCommitSHA)
 }
 
-func inspectMergeStrategy(ctx context.Context, prNumber int, mergeCommitSHA string) (MergeStrategy, error) {
-	nameWithOwner, err := GetNameWithOwner(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get repository name with owner: %w", err)
-	}
-
-	hostname, err := GetGHHostname(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get GH hostname: %w", err)
-	}
-
-	prevCommitSHA, err := ghAPIQuery(ctx, hostname,
-		fmt.Sprintf("repos/%s/commits/%s", nameWithOwner, mergeCommitSHA),
+func inspectMergeStrategy(ctx context.Context, repo gitobj.Repository, prNumber int, mergeCommitSHA string) (MergeStrategy, error) {
+	prevCommitSHA, err := ghAPIQuery(ctx, repo.Host,
+		fmt.Sprintf("repos/%s/commits/%s", repo.NameWithOwner(), mergeCommitSHA),
 		".parents[0].sha",
 		nil,
 	)
@@ -60,8 +52,8 @@ func inspectMergeStrategy(ctx context.Context, prNumber int, mergeCommitSHA stri
 		return "", fmt.Errorf("failed to get previous commit SHA for merge commit %s: %w", mergeCommitSHA, err)
 	}
 
-	prNumbersStr, err := ghAPIQuery(ctx, hostname,
-		fmt.Sprintf("repos/%s/commits/%s/pulls", nameWithOwner, prevCommitSHA),
+	prNumbersStr, err := ghAPIQuery(ctx, repo.Host,
+		fmt.Sprintf("repos/%s/commits/%s/pulls", repo.NameWithOwner(), prevCommitSHA),
 		".[].number",
 		map[string]string{"Accept": "application/vnd.github+json"},
 	)
@@ -0,0 +1,254 @@
+package git
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// maxPrerequisiteCommits bounds the commits looked up as prerequisites, as
+// each one is an API call.
+const maxPrerequisiteCommits = 20
+
+// Prerequisite is a PR of the base branch which changed the lines that the
+// conflicting hunks expect, and which is missing on the target branch.
+type Prerequisite struct {
+	Number int
+	Title  string
+	URL    string
+	// Commits are the commits of the PR which changed those lines.
+	Commits []string
+
+	pr *gitobj.PullRequest
+}
+
+// handleConflicts runs the resolvers on the conflicts of an operation, and
+// reports those which remain with the missing prerequisite PRs. With
+// IncludePrerequisites, it cherry-picks the prerequisites onto start instead,
+// and retries the operation.
+func (cherryPick *CherryPick) handleConflicts(ctx context.Context, err error, start string, continueOperation, retry func() error) error {
+	err = cherryPick.resolvers.resolveConflicts(ctx, err, continueOperation)
+
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) || cherryPick.pr == nil || cherryPick.includingPrerequisites {
+		return reportConflicts(ctx, err, "")
+	}
+
+	logger := log.LoggerFromCtx(ctx)
+	prerequisites, findErr := cherryPick.findPrerequisites(ctx, logger)
+	if findErr != nil {
+		logger.WithError(findErr).Warnf("could not look for prerequisite PRs")
+	}
+	if len(prerequisites) == 0 {
+		return reportConflicts(ctx, err, "")
+	}
+
+	cherryPick.Result.Prerequisites = prerequisites
+	if cherryPick.IncludePrerequisites {
+		return cherryPick.includePrerequisites(ctx, logger, start, prerequisites, retry)
+	}
+	return reportConflicts(ctx, err, prerequisiteSuggestion(cherryPick.pr.BaseRefName, cherryPick.OnTo, prerequisites))
+}
+
+// findPrerequisites blames the lines which the conflicting hunks expect on
+// the base branch, since it diverged from the target branch, and returns the
+// PRs of those changes which are not on the target branch, oldest first.
+func (cherryPick *CherryPick) findPrerequisites(ctx context.Context, logger log.Logger) ([]Prerequisite, error) {
+	parent := cherryPick.prParent()
+	if parent == "" {
+		return nil, nil
+	}
+	target := "origin/" + cherryPick.OnTo
+	mergeBase, err := MergeBase(ctx, target, parent)
+	if err != nil {
+		return nil, fmt.Errorf("error finding where %s diverged from %s: %w", cherryPick.pr.BaseRefName, cherryPick.OnTo, err)
+	}
+
+	root, err := GetRepoRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = CtxWithDir(ctx, root)
+
+	logger.Infof("looking for prerequisite PRs")
+	commits, err := blameConflictBases(ctx, mergeBase, parent)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) > maxPrerequisiteCommits {
+		logger.Warnf("only looking up the %d newest of %d commits", maxPrerequisiteCommits, len(commits))
+		commits = commits[len(commits)-maxPrerequisiteCommits:]
+	}
+
+	subjects, err := RevListSubjects(ctx, mergeBase+".."+target)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the commits of %s: %w", cherryPick.OnTo, err)
+	}
+
+	var prerequisites []Prerequisite
+	for _, commit := range commits {
+		number, err := GetPullRequestNumberForCommit(ctx, cherryPick.repo, commit.sha)
+		if err != nil {
+			return nil, err
+		}
+		if number == 0 || number == cherryPick.pr.Number {
+			continue
+		}
+		if i := slices.IndexFunc(prerequisites, func(p Prerequisite) bool { return p.Number == number }); i >= 0 {
+			prerequisites[i].Commits = append(prerequisites[i].Commits, commit.sha)
+			continue
+		}
+
+		if hasBackportSubject(subjects, number) || isPatchOnBranch(ctx, target, commit.sha) {
+			logger.WithField("pr", number).Infof("already on %s", cherryPick.OnTo)
+			continue
+		}
+
+		pr, err := GetPullRequest(ctx, cherryPick.repo, number)
+		if err != nil {
+			return nil, err
+		}
+		prerequisites = append(prerequisites, Prerequisite{Number: number, Title: pr.Title, URL: pr.Url, Commits: []string{commit.sha}, pr: pr})
+		logger.WithField("commit", commit.sha[:7]).Warnf("%s %s is missing on %s", pr.PRNumberString(), pr.Title, cherryPick.OnTo)
+	}
+	return prerequisites, nil
+}
+
+// prParent returns the commit of the base branch which the changes of the PR
+// apply on, or an empty string when it is unknown.
+func (cherryPick *CherryPick) prParent() string {
+	pr := cherryPick.pr
+	if pr.State == gitobj.PullRequestStateMerged && pr.MergeCommit.Sha != "" {
+		return pr.MergeCommit.Sha + "^"
+	}
+	if len(pr.Commits) > 0 {
+		return pr.Commits[0].Oid + "^"
+	}
+	return ""
+}
+
+// blameConflictBases blames the merge base side of each conflicting hunk at
+// parent, and returns the commits since mergeBase which changed those lines,
+// oldest first.
+func blameConflictBases(ctx context.Context, mergeBase, parent string) ([]blamedCommit, error) {
+	files, err := ConflictedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the conflicted files: %w", err)
+	}
+
+	var commits []blamedCommit
+	for _, file := range files {
+		stages, err := unmergedStages(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(stages, 1) || !slices.Contains(stages, 2) || !slices.Contains(stages, 3) {
+			continue
+		}
+
+		// The diff3 style has the base side of each hunk, whatever the
+		// conflict style of the worktree.
+		merged, err := mergeStages(ctx, file, true, "--diff3")
+		if err != nil {
+			return nil, err
+		}
+
+		var blocks [][]string
+		for _, hunk := range parseConflictHunks(string(merged)) {
+			blocks = append(blocks, hunk.base)
+		}
+		for _, blamed := range blameBlocks(ctx, file, mergeBase, parent, blocks) {
+			for _, commit := range blamed {
+				if !commit.boundary && !slices.ContainsFunc(commits, func(c blamedCommit) bool { return c.sha == commit.sha }) {
+					commits = append(commits, commit)
+				}
+			}
+		}
+	}
+
+	slices.SortStableFunc(commits, func(a, b blamedCommit) int { return cmp.Compare(a.time, b.time) })
+	return commits, nil
+}
+
+// hasBackportSubject reports whether a subject refers to the PR like the
+// squashed commits of GitHub and the backports do, e.g. "Fix it (#123)".
+func hasBackportSubject(subjects []string, number int) bool {
+	ref := fmt.Sprintf("(#%d)", number)
+	return slices.ContainsFunc(subjects, func(subject string) bool { return strings.Contains(subject, ref) })
+}
+
+// isPatchOnBranch reports whether an equivalent change of the commit is on
+// the branch, comparing their patch IDs.
+func isPatchOnBranch(ctx context.Context, branch, sha string) bool {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "cherry", branch, sha, sha+"^").Run(ctx, WithStdout(stdout)); err != nil {
+		return false
+	}
+	return strings.HasPrefix(stdout.String(), "- ")
+}
+
+// includePrerequisites cherry-picks the prerequisites onto start, oldest
+// first, and retries the operation on top of them.
+func (cherryPick *CherryPick) includePrerequisites(ctx context.Context, logger log.Logger, start string, prerequisites []Prerequisite, retry func() error) error {
+	cherryPick.includingPrerequisites = true
+
+	if err := abortOperation(ctx, logger); err != nil {
+		return err
+	}
+	if err := NewCommand("git", "reset", "--hard", start).Run(ctx); err != nil {
+		return fmt.Errorf("error resetting to %s: %w", start, err)
+	}
+
+	refs := make([]string, 0, len(prerequisites))
+	for _, p := range prerequisites {
+		commits, err := cherryPick.prerequisiteCommits(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		logger.WithField("commits", len(commits)).Infof("cherry-picking the prerequisite %s %s", p.pr.PRNumberString(), p.Title)
+		if err = cherryPick.cherryPickCommits(ctx, commits...); err != nil {
+			return fmt.Errorf("error cherry-picking the prerequisite %s\n%w", p.pr.PRNumberString(), err)
+		}
+		refs = append(refs, fmt.Sprintf("#%d", p.Number))
+	}
+
+	if cherryPick.desc != nil {
+		cherryPick.desc.Notes = append(cherryPick.desc.Notes, fmt.Sprintf("Includes the prerequisite PR(s) %s, which the changes build on.", strings.Join(refs, ", ")))
+	}
+	logger.Successf("included the prerequisite PR(s) %s. retrying", strings.Join(refs, ", "))
+	return retry()
+}
+
+// prerequisiteCommits returns the commits to cherry-pick for a prerequisite:
+// its own commits when it was rebased, or its merge commit.
+func (cherryPick *CherryPick) prerequisiteCommits(ctx context.Context, p Prerequisite) ([]string, error) {
+	mergeCommit := p.pr.MergeCommit.Sha
+	strategy, err := PRMergedWith(ctx, cherryPick.repo, p.Number)
+	if err != nil {
+		return nil, err
+	}
+	if strategy == MergeStrategyRebase && len(p.pr.Commits) > 1 {
+		return RevList(ctx, fmt.Sprintf("%s~%d..%s", mergeCommit, len(p.pr.Commits), mergeCommit))
+	}
+	return []string{mergeCommit}, nil
+}
+
+// prerequisiteSuggestion tells to backport the prerequisites first.
+func prerequisiteSuggestion(base, onTo string, prerequisites []Prerequisite) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "the conflicting lines were changed on %s by PR(s) missing on %s:\n", base, onTo)
+	for _, p := range prerequisites {
+		fmt.Fprintf(&sb, "  • backport %s first: %s %s\n", color.Cyan(fmt.Sprintf("#%d", p.Number)), p.Title, color.Grey(p.URL))
+	}
+	fmt.Fprintf(&sb, "or run again with %s to cherry-pick them first\n", color.Yellow("-include-prerequisites"))
+	return sb.String()
+}
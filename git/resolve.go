@@ -0,0 +1,357 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// resolverConfigKey is the multi-valued git config of the resolvers, e.g.
+// git config --add gh-cherry-pick.resolve 'CHANGELOG.md=union'.
+const resolverConfigKey = "gh-cherry-pick.resolve"
+
+// ResolveStrategy is how a resolver resolves a conflicted path.
+type ResolveStrategy string
+
+const (
+	// ResolveOurs keeps the version of the target branch.
+	ResolveOurs ResolveStrategy = "ours"
+	// ResolveTheirs takes the version of the cherry-picked commit.
+	ResolveTheirs ResolveStrategy = "theirs"
+	// ResolveUnion keeps the lines of both sides, e.g. for changelogs.
+	ResolveUnion ResolveStrategy = "union"
+	// ResolveRun runs a command which rewrites the paths, e.g. go mod tidy.
+	ResolveRun ResolveStrategy = "run"
+)
+
+// Resolver resolves the conflicts of the paths matching Glob. A glob without
+// a slash matches the base name at any depth; otherwise it matches the whole
+// path, with * matching across directories.
+type Resolver struct {
+	Glob     string
+	Strategy ResolveStrategy
+	// Command is run by ResolveRun with sh, from the top of the worktree,
+	// with the conflicted paths as arguments.
+	Command string
+}
+
+// ParseResolver parses GLOB=STRATEGY, where STRATEGY is ours, theirs, union
+// or run:COMMAND.
+func ParseResolver(s string) (Resolver, error) {
+	glob, strategy, ok := strings.Cut(s, "=")
+	glob, strategy = strings.TrimSpace(glob), strings.TrimSpace(strategy)
+	if !ok || glob == "" || strategy == "" {
+		return Resolver{}, fmt.Errorf("invalid resolver %q: expected GLOB=STRATEGY, e.g. CHANGELOG.md=union or go.sum=run:go mod tidy", s)
+	}
+
+	if command, ok := strings.CutPrefix(strategy, string(ResolveRun)+":"); ok {
+		if strings.TrimSpace(command) == "" {
+			return Resolver{}, fmt.Errorf("invalid resolver %q: the command is empty", s)
+		}
+		return Resolver{Glob: glob, Strategy: ResolveRun, Command: strings.TrimSpace(command)}, nil
+	}
+
+	switch r := (Resolver{Glob: glob, Strategy: ResolveStrategy(strategy)}); r.Strategy {
+	case ResolveOurs, ResolveTheirs, ResolveUnion:
+		return r, nil
+	default:
+		return Resolver{}, fmt.Errorf("invalid resolver %q: strategy must be one of ours, theirs, union, run:COMMAND", s)
+	}
+}
+
+func (r Resolver) String() string {
+	if r.Strategy == ResolveRun {
+		return fmt.Sprintf("%s=%s:%s", r.Glob, r.Strategy, r.Command)
+	}
+	return fmt.Sprintf("%s=%s", r.Glob, r.Strategy)
+}
+
+func (r Resolver) matches(file string) bool {
+	if !strings.Contains(r.Glob, "/") {
+		matched, _ := filepath.Match(r.Glob, file[strings.LastIndex(file, "/")+1:])
+		return matched
+	}
+	return matchGlob(r.Glob, file)
+}
+
+// Resolvers are tried in order; the first matching one resolves a path.
+type Resolvers []Resolver
+
+func (resolvers *Resolvers) String() string {
+	if resolvers == nil {
+		return ""
+	}
+	items := make([]string, 0, len(*resolvers))
+	for _, r := range *resolvers {
+		items = append(items, r.String())
+	}
+	return strings.Join(items, ", ")
+}
+
+// Set adds the resolver of a -resolve flag, which may be repeated.
+func (resolvers *Resolvers) Set(s string) error {
+	resolver, err := ParseResolver(s)
+	if err != nil {
+		return err
+	}
+	*resolvers = append(*resolvers, resolver)
+	return nil
+}
+
+// LoadResolvers returns the resolvers of the gh-cherry-pick.resolve git config.
+func LoadResolvers(ctx context.Context) (Resolvers, error) {
+	var resolvers Resolvers
+	for _, value := range GetConfigAll(ctx, resolverConfigKey) {
+		resolver, err := ParseResolver(value)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", resolverConfigKey, err)
+		}
+		resolvers = append(resolvers, resolver)
+	}
+	return resolvers, nil
+}
+
+// resolve resolves the conflicted paths it has a resolver for, and returns
+// those left unresolved.
+func (resolvers Resolvers) resolve(ctx context.Context, logger log.Logger) ([]string, error) {
+	root, err := GetRepoRoot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Conflicted paths are relative to the top of the worktree.
+	ctx = CtxWithDir(ctx, root)
+
+	conflicted, err := ConflictedFiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the conflicted files: %w", err)
+	}
+
+	var unresolved []string
+	// The paths of each command, so that it runs once for all of them.
+	commands := map[int][]string{}
+	for _, file := range conflicted {
+		i := slices.IndexFunc(resolvers, func(r Resolver) bool { return r.matches(file) })
+		if i < 0 {
+			unresolved = append(unresolved, file)
+			continue
+		}
+
+		resolver := resolvers[i]
+		if resolver.Strategy == ResolveRun {
+			commands[i] = append(commands[i], file)
+			continue
+		}
+
+		logger.WithField("resolver", resolver).Infof("resolving %s", file)
+		if err = resolver.resolveFile(ctx, file); err != nil {
+			logger.WithError(err).Warnf("could not resolve %s", file)
+			unresolved = append(unresolved, file)
+		}
+	}
+
+	for i, resolver := range resolvers {
+		files, ok := commands[i]
+		if !ok {
+			continue
+		}
+
+		logger.WithField("resolver", resolver).Infof("resolving %s", strings.Join(files, ", "))
+		args := append([]string{"-c", resolver.Command, "sh"}, files...)
+		if err = NewCommand("sh", args...).Run(ctx); err != nil {
+			logger.WithError(err).Warnf("could not resolve %s", strings.Join(files, ", "))
+			unresolved = append(unresolved, files...)
+			continue
+		}
+		for _, file := range files {
+			if err = addResolved(ctx, file); err != nil {
+				logger.WithError(err).Warnf("could not resolve %s", file)
+				unresolved = append(unresolved, file)
+			}
+		}
+	}
+
+	return unresolved, nil
+}
+
+func (r Resolver) resolveFile(ctx context.Context, file string) error {
+	stages, err := unmergedStages(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	switch r.Strategy {
+	case ResolveOurs, ResolveTheirs:
+		stage, option := 2, "--ours"
+		if r.Strategy == ResolveTheirs {
+			stage, option = 3, "--theirs"
+		}
+		if !slices.Contains(stages, stage) {
+			// The side deleted the path.
+			return NewCommand("git", "rm", "--quiet", "--", file).Run(ctx)
+		}
+		if err = NewCommand("git", "checkout", option, "--", file).Run(ctx); err != nil {
+			return err
+		}
+		return NewCommand("git", "add", "--", file).Run(ctx)
+
+	case ResolveUnion:
+		if !slices.Contains(stages, 2) || !slices.Contains(stages, 3) {
+			return errors.New("union needs both sides, but one side deleted the path")
+		}
+		if err = mergeUnion(ctx, file, slices.Contains(stages, 1)); err != nil {
+			return err
+		}
+		return addResolved(ctx, file)
+	}
+	return fmt.Errorf("unsupported strategy %q", r.Strategy)
+}
+
+// unmergedStages returns the index stages of a conflicted path: 1 for the
+// base, 2 for ours and 3 for theirs.
+func unmergedStages(ctx context.Context, file string) ([]int, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "ls-files", "--unmerged", "-z", "--", file).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, err
+	}
+
+	var stages []int
+	for _, entry := range strings.Split(stdout.String(), "\x00") {
+		// <mode> <object> <stage>\t<path>
+		info, _, ok := strings.Cut(entry, "\t")
+		if fields := strings.Fields(info); ok && len(fields) == 3 {
+			var stage int
+			if _, err := fmt.Sscan(fields[2], &stage); err == nil {
+				stages = append(stages, stage)
+			}
+		}
+	}
+	return stages, nil
+}
+
+// mergeUnion merges both sides of a conflicted path keeping the lines of both.
+// The merged file keeps the mode of our side, e.g. executable.
+func mergeUnion(ctx context.Context, file string, hasBase bool) error {
+	merged, err := mergeStages(ctx, file, hasBase, "--union")
+	if err != nil {
+		return err
+	}
+	mode, err := oursMode(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(DirFromCtx(ctx), file)
+	if err = os.WriteFile(path, merged, mode); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file.
+	return os.Chmod(path, mode)
+}
+
+// oursMode returns the file mode of our side of a conflicted path.
+func oursMode(ctx context.Context, file string) (os.FileMode, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "ls-files", "--unmerged", "-z", "--", file).Run(ctx, WithStdout(stdout)); err != nil {
+		return 0, err
+	}
+
+	for _, entry := range strings.Split(stdout.String(), "\x00") {
+		// <mode> <object> <stage>\t<path>
+		info, _, _ := strings.Cut(entry, "\t")
+		if fields := strings.Fields(info); len(fields) == 3 && fields[2] == "2" && fields[0] == "100755" {
+			return 0755, nil
+		}
+	}
+	return 0644, nil
+}
+
+// mergeStages merges the index stages of a conflicted path with git
+// merge-file and the given option, and returns the result. A missing base is
+// merged as empty.
+func mergeStages(ctx context.Context, file string, hasBase bool, option string) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "gh-cherry-pick-merge-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	versions := map[int]string{}
+	for _, stage := range []int{1, 2, 3} {
+		versions[stage] = filepath.Join(tmpDir, fmt.Sprint(stage))
+		var content bytes.Buffer
+		if stage != 1 || hasBase {
+			if err = NewCommand("git", "show", fmt.Sprintf(":%d:%s", stage, file)).Run(ctx, WithStdout(&content)); err != nil {
+				return nil, err
+			}
+		}
+		if err = os.WriteFile(versions[stage], content.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := &bytes.Buffer{}
+	err = NewCommand("git", "merge-file", option, "-p", versions[2], versions[1], versions[3]).Run(ctx, WithStdout(merged))
+	// merge-file exits with the number of conflicts left.
+	var gitError *GitError
+	if err != nil && !(errors.As(err, &gitError) && gitError.ExitCode > 0 && gitError.ExitCode < 128) {
+		return nil, err
+	}
+	return merged.Bytes(), nil
+}
+
+// addResolved stages a path which a resolver rewrote, unless it still has
+// conflict markers.
+func addResolved(ctx context.Context, file string) error {
+	content, err := os.ReadFile(filepath.Join(DirFromCtx(ctx), file))
+	if errors.Is(err, os.ErrNotExist) {
+		return NewCommand("git", "rm", "--quiet", "--", file).Run(ctx)
+	} else if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return errors.New("it still has conflict markers")
+		}
+	}
+	return NewCommand("git", "add", "--", file).Run(ctx)
+}
+
+// resolveConflicts runs the resolvers each time an operation stops on
+// conflicts, and continues it once none remain. It returns the error of the
+// operation when some conflicts have no resolver.
+func (resolvers Resolvers) resolveConflicts(ctx context.Context, err error, continueOperation func() error) error {
+	logger := log.LoggerFromCtx(ctx)
+
+	for range maxConflictedSteps {
+		var conflictErr *ConflictError
+		if len(resolvers) == 0 || !errors.As(err, &conflictErr) {
+			return err
+		}
+
+		logger.Infof("resolving conflicts")
+		unresolved, resolveErr := resolvers.resolve(ctx, logger)
+		if resolveErr != nil {
+			return fmt.Errorf("%w\n\nerror resolving conflicts: %w", err, resolveErr)
+		}
+		if len(unresolved) > 0 {
+			logger.WithField("files", strings.Join(unresolved, ", ")).Warnf("conflicts remain")
+			return &ConflictError{
+				message: fmt.Sprintf("no resolver for the conflicts in %s\n%s", color.Cyan(strings.Join(unresolved, ", ")), conflictErr.message),
+				err:     conflictErr.err,
+			}
+		}
+
+		logger.Successf("resolved all conflicts. continuing")
+		err = continueOperation()
+	}
+	return fmt.Errorf("still conflicting after resolving %d times: %w", maxConflictedSteps, err)
+}
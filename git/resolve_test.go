@@ -0,0 +1,115 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseResolver(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected Resolver
+		wantErr  bool
+	}{{
+		name:     "union",
+		input:    "CHANGELOG.md=union",
+		expected: Resolver{Glob: "CHANGELOG.md", Strategy: ResolveUnion},
+	}, {
+		name:     "run with spaces",
+		input:    " go.sum = run: go mod tidy ",
+		expected: Resolver{Glob: "go.sum", Strategy: ResolveRun, Command: "go mod tidy"},
+	}, {
+		name:     "run with equals in the command",
+		input:    "*.lock=run:FOO=1 make lock",
+		expected: Resolver{Glob: "*.lock", Strategy: ResolveRun, Command: "FOO=1 make lock"},
+	}, {
+		name:    "missing strategy",
+		input:   "CHANGELOG.md",
+		wantErr: true,
+	}, {
+		name:    "unknown strategy",
+		input:   "CHANGELOG.md=mine",
+		wantErr: true,
+	}, {
+		name:    "empty command",
+		input:   "go.sum=run:",
+		wantErr: true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ParseResolver(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolverMatches(t *testing.T) {
+	testcases := []struct {
+		glob     string
+		file     string
+		expected bool
+	}{
+		{glob: "CHANGELOG.md", file: "CHANGELOG.md", expected: true},
+		{glob: "CHANGELOG.md", file: "docs/CHANGELOG.md", expected: true},
+		{glob: "*.lock", file: "web/yarn.lock", expected: true},
+		{glob: "docs/*", file: "docs/api/index.md", expected: true},
+		{glob: "docs/*", file: "web/docs/index.md", expected: false},
+		{glob: "go.sum", file: "go.mod", expected: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.glob+" "+tc.file, func(t *testing.T) {
+			if actual := (Resolver{Glob: tc.glob}).matches(tc.file); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMergeUnion(t *testing.T) {
+	testcases := []struct {
+		name     string
+		chmod    string
+		expected os.FileMode
+	}{{
+		name:     "regular",
+		chmod:    "-x",
+		expected: 0644,
+	}, {
+		name:     "executable",
+		chmod:    "+x",
+		expected: 0755,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, sh := newTestRepo(t)
+			sh("git checkout -q release && chmod " + tc.chmod + " a.txt && printf '1\\n2\\n3\\nr\\n' > a.txt && git commit -qam r && (git cherry-pick feature~1 || true)")
+			// The mode is of our side in the index, not of the file in the worktree.
+			sh("rm a.txt")
+
+			if err := mergeUnion(ctx, "a.txt", true); err != nil {
+				t.Fatal(err)
+			}
+			if content := sh("cat a.txt"); content != "1\n2\n3\nr\n4" {
+				t.Errorf("expected the lines of both sides, got %q", content)
+			}
+			info, err := os.Stat(filepath.Join(DirFromCtx(ctx), "a.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := info.Mode().Perm() &^ 0022; mode != tc.expected {
+				t.Errorf("expected mode %v, got %v", tc.expected, mode)
+			}
+		})
+	}
+}
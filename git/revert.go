@@ -0,0 +1,217 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+// provenancePattern matches the trailer of git cherry-pick -x, which the
+// backports record.
+var provenancePattern = regexp.MustCompile(`\(cherry picked from commit ([0-9a-f]{40})\)`)
+
+// runRevert reverts the backport of the merged PR on OnTo on a new branch.
+func (cherryPick *CherryPick) runRevert(ctx context.Context, sourceRemote string, pr *gitobj.PullRequest, desc description) error {
+	logger := log.LoggerFromCtx(ctx)
+
+	var backports []string
+	err := tui.WithStep(ctx, "finding the backport", func(ctx context.Context, logger log.Logger) error {
+		logger.WithField("branch", pr.BaseRefName).Infof("fetching the branch")
+		if err := Fetch(ctx, sourceRemote, pr.BaseRefName); err != nil {
+			return fmt.Errorf("error fetching the branch '%s': %w", pr.BaseRefName, err)
+		}
+		logger.WithField("branch", cherryPick.OnTo).Infof("fetching the branch")
+		if err := Fetch(ctx, "origin", cherryPick.OnTo); err != nil {
+			return fmt.Errorf("error fetching the branch '%s': %w", cherryPick.OnTo, err)
+		}
+
+		prCommits, err := prCommitsOnBase(ctx, cherryPick.repo, pr)
+		if err != nil {
+			return err
+		}
+		if backports, err = findBackports(ctx, "origin/"+cherryPick.OnTo, prCommits); err != nil {
+			return err
+		}
+		if len(backports) == 0 {
+			return fmt.Errorf("no backport of %s found on %s, or it is reverted already", desc.PRRef, cherryPick.OnTo)
+		}
+
+		logger.Successf("found %d backported commit(s) on %s", len(backports), color.Cyan(cherryPick.OnTo))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var branchName = fmt.Sprintf("revert-pr-%d-on-%s-%d", pr.Number, strings.ReplaceAll(cherryPick.OnTo, "/", "-"), time.Now().Unix())
+	err = tui.WithStep(ctx, "checking out branch", func(ctx context.Context, logger log.Logger) error {
+		return cherryPick.checkoutBranch(ctx, logger, branchName, true)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tui.WithStep(ctx, "reverting the backport", func(ctx context.Context, logger log.Logger) error {
+		// The newest commit is reverted first.
+		commits := slices.Clone(backports)
+		slices.Reverse(commits)
+
+		logger.WithField("commits", len(commits)).Infof("reverting")
+		return revertCommits(ctx, commits...)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Successf("reverted the backport of %s on branch %s", desc.PRRef, color.Cyan(branchName))
+
+	short := make([]string, 0, len(backports))
+	for _, commit := range backports {
+		short = append(short, commit[:7])
+	}
+	desc.Revert = true
+	desc.Notes = append(desc.Notes, fmt.Sprintf("Reverts %s.", strings.Join(short, ", ")))
+	return cherryPick.finish(ctx, branchName, desc)
+}
+
+// prCommitsOnBase returns the commits which a merged PR landed as on its
+// base branch: its merge commit when squashed, its rebased commits, or the
+// commits of its merge commit.
+func prCommitsOnBase(ctx context.Context, repo gitobj.Repository, pr *gitobj.PullRequest) ([]string, error) {
+	mergeCommit := pr.MergeCommit.Sha
+
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "rev-list", "--parents", "-n", "1", mergeCommit).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, fmt.Errorf("error getting the parents of %s: %w", mergeCommit, err)
+	}
+	if parents := strings.Fields(stdout.String())[1:]; len(parents) > 1 {
+		return RevList(ctx, fmt.Sprintf("%s..%s", parents[0], parents[1]))
+	}
+
+	if len(pr.Commits) > 1 {
+		strategy, err := PRMergedWith(ctx, repo, pr.Number)
+		if err != nil {
+			return nil, err
+		}
+		if strategy == MergeStrategyRebase {
+			return RevList(ctx, fmt.Sprintf("%s~%d..%s", mergeCommit, len(pr.Commits), mergeCommit))
+		}
+	}
+	return []string{mergeCommit}, nil
+}
+
+// findBackports returns the commits of target, since it diverged from the
+// PR commits, which were backported from them, oldest first. A backport is
+// recognized by the trailer of git cherry-pick -x, or by the same patch as a
+// PR commit or as all of them squashed. Backports reverted already are left
+// out.
+func findBackports(ctx context.Context, target string, prCommits []string) ([]string, error) {
+	mergeBase, err := MergeBase(ctx, target, prCommits[len(prCommits)-1])
+	if err != nil {
+		return nil, fmt.Errorf("error finding where %s diverged: %w", target, err)
+	}
+	candidates := mergeBase + ".." + target
+
+	ids, err := patchIDsOf(ctx, append([]string{"log", "-p", "--no-walk=unsorted", "--format=commit %H", "--end-of-options"}, prCommits...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(prCommits) > 1 {
+		squashed, err := patchIDsOf(ctx, "diff", prCommits[0]+"^", prCommits[len(prCommits)-1])
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, squashed...)
+	}
+	var prIDs []string
+	for _, id := range ids {
+		prIDs = append(prIDs, id.patchID)
+	}
+
+	targetIDs, err := patchIDsOf(ctx, "log", "-p", "--no-merges", "--format=commit %H", candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &bytes.Buffer{}
+	if err = NewCommand("git", "log", "--reverse", "--no-merges", "--format=%H%x00%B%x1e", candidates).Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, fmt.Errorf("error listing the commits of %s: %w", candidates, err)
+	}
+
+	var backports, reverted []string
+	for _, entry := range strings.Split(stdout.String(), "\x1e") {
+		sha, message, ok := strings.Cut(strings.TrimSpace(entry), "\x00")
+		if !ok {
+			continue
+		}
+
+		for _, match := range revertPattern.FindAllStringSubmatch(message, -1) {
+			reverted = append(reverted, match[1])
+		}
+
+		isBackport := slices.ContainsFunc(provenancePattern.FindAllStringSubmatch(message, -1), func(match []string) bool {
+			return slices.Contains(prCommits, match[1])
+		})
+		if !isBackport {
+			i := slices.IndexFunc(targetIDs, func(id patchID) bool { return id.commit == sha })
+			isBackport = i >= 0 && slices.Contains(prIDs, targetIDs[i].patchID)
+		}
+		if isBackport {
+			backports = append(backports, sha)
+		}
+	}
+
+	return slices.DeleteFunc(backports, func(sha string) bool {
+		return slices.ContainsFunc(reverted, func(prefix string) bool { return strings.HasPrefix(sha, prefix) })
+	}), nil
+}
+
+type patchID struct {
+	patchID string
+	commit  string
+}
+
+// patchIDsOf pipes the output of the git command into git patch-id --stable.
+func patchIDsOf(ctx context.Context, args ...string) ([]patchID, error) {
+	patches := &bytes.Buffer{}
+	if err := NewCommand("git", args...).Run(ctx, WithStdout(patches)); err != nil {
+		return nil, fmt.Errorf("error getting the patches: %w", err)
+	}
+
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "patch-id", "--stable").Run(ctx, WithStdin(patches), WithStdout(stdout)); err != nil {
+		return nil, fmt.Errorf("error computing the patch IDs: %w", err)
+	}
+
+	var ids []patchID
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			ids = append(ids, patchID{patchID: fields[0], commit: fields[1]})
+		}
+	}
+	return ids, nil
+}
+
+// revertCommits runs git revert for the given commits, in order.
+func revertCommits(ctx context.Context, commits ...string) error {
+	err := NewCommand("git", append([]string{"revert", "--no-edit"}, commits...)...).Run(ctx)
+	if err == nil {
+		return nil
+	}
+
+	helpMsg := fmt.Sprintf("run %v after resolve the conflicts\nrun %v if you want to abort the revert", color.Green("`git revert --continue`"), color.Yellow("`git revert --abort`"))
+	var gitError *GitError
+	if errors.As(err, &gitError) && gitError.ExitCode == 1 && strings.Contains(gitError.Stderr, "error: could not revert") {
+		return reportConflicts(ctx, &ConflictError{message: helpMsg, err: err}, "")
+	}
+	return fmt.Errorf("%s\n\n%w", helpMsg, err)
+}
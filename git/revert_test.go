@@ -0,0 +1,127 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+// newTestRepo creates a repository with the branch feature of two commits
+// off main, and the branch release with an unrelated commit.
+func newTestRepo(t *testing.T) (context.Context, func(script string) string) {
+	t.Helper()
+	for key, value := range map[string]string{
+		"GIT_AUTHOR_NAME": "a", "GIT_AUTHOR_EMAIL": "a@example.com",
+		"GIT_COMMITTER_NAME": "a", "GIT_COMMITTER_EMAIL": "a@example.com",
+		"GIT_CONFIG_GLOBAL": "/dev/null", "GIT_CONFIG_NOSYSTEM": "1",
+	} {
+		t.Setenv(key, value)
+	}
+
+	dir := t.TempDir()
+	sh := func(script string) string {
+		t.Helper()
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", script, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	sh(`git init -q -b main &&
+printf '1\n2\n3\n' > a.txt && git add a.txt && git commit -qm init &&
+git checkout -qb release && echo release > release.txt && git add release.txt && git commit -qm release &&
+git checkout -qb feature main && printf '1\n2\n3\n4\n' > a.txt && git commit -qam 'append 4' &&
+echo b > b.txt && git add b.txt && git commit -qm 'add b' &&
+git checkout -q main && echo main > main.txt && git add main.txt && git commit -qm main`)
+	return CtxWithDir(context.Background(), dir), sh
+}
+
+func TestFindBackports(t *testing.T) {
+	testcases := []struct {
+		name string
+		// land merges feature into main, and returns the PR commits on main.
+		land func(ctx context.Context, t *testing.T, sh func(string) string) []string
+		// backport backports them onto release, and returns the backports
+		// which are expected to be found.
+		backport func(sh func(string) string, prCommits []string) []string
+	}{{
+		name: "merge commit cherry-picked with -x",
+		land: func(ctx context.Context, t *testing.T, sh func(string) string) []string {
+			sh("git merge -q --no-ff -m 'Merge feature' feature")
+			pr := &gitobj.PullRequest{Commits: make([]gitobj.Commit, 2)}
+			pr.MergeCommit.Sha = sh("git rev-parse HEAD")
+			prCommits, err := prCommitsOnBase(ctx, gitobj.Repository{}, pr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := strings.Fields(sh("git rev-list --reverse main^..feature")); !reflect.DeepEqual(prCommits, expected) {
+				t.Fatalf("expected the PR commits %v, got %v", expected, prCommits)
+			}
+			return prCommits
+		},
+		backport: func(sh func(string) string, prCommits []string) []string {
+			sh("git checkout -q release && git cherry-pick -x " + strings.Join(prCommits, " "))
+			return strings.Fields(sh("git rev-list --reverse HEAD~2..HEAD"))
+		},
+	}, {
+		name: "squash merge cherry-picked without -x",
+		land: func(ctx context.Context, t *testing.T, sh func(string) string) []string {
+			sh("git merge -q --squash feature && git commit -qm 'Feature (#1)'")
+			pr := &gitobj.PullRequest{Commits: make([]gitobj.Commit, 1)}
+			pr.MergeCommit.Sha = sh("git rev-parse HEAD")
+			prCommits, err := prCommitsOnBase(ctx, gitobj.Repository{}, pr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return prCommits
+		},
+		backport: func(sh func(string) string, prCommits []string) []string {
+			sh("git checkout -q release && git cherry-pick " + prCommits[0])
+			return []string{sh("git rev-parse HEAD")}
+		},
+	}, {
+		name: "rebase merge squashed onto the target",
+		land: func(ctx context.Context, t *testing.T, sh func(string) string) []string {
+			sh("git cherry-pick main..feature")
+			return strings.Fields(sh("git rev-list --reverse HEAD~2..HEAD"))
+		},
+		backport: func(sh func(string) string, prCommits []string) []string {
+			sh("git checkout -q release && git diff " + prCommits[0] + "^ " + prCommits[1] + " | git apply --index && git commit -qm 'Feature (#1)'")
+			return []string{sh("git rev-parse HEAD")}
+		},
+	}, {
+		name: "backport reverted already",
+		land: func(ctx context.Context, t *testing.T, sh func(string) string) []string {
+			sh("git merge -q --squash feature && git commit -qm 'Feature (#1)'")
+			return []string{sh("git rev-parse HEAD")}
+		},
+		backport: func(sh func(string) string, prCommits []string) []string {
+			sh("git checkout -q release && git cherry-pick -x " + prCommits[0] + " && git revert --no-edit HEAD")
+			return nil
+		},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, sh := newTestRepo(t)
+			prCommits := tc.land(ctx, t, sh)
+			expected := tc.backport(sh, prCommits)
+
+			backports, err := findBackports(ctx, "release", prCommits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(backports, expected) {
+				t.Errorf("expected %v, got %v", expected, backports)
+			}
+		})
+	}
+}
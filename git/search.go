@@ -0,0 +1,104 @@
+package git
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+const (
+	// searchPageSize is the most PRs one page of the search API returns.
+	searchPageSize = 100
+	// maxSearchResults is the most PRs the search API returns over all pages.
+	maxSearchResults = 1000
+)
+
+// SearchResult is a merged PR found by SearchPullRequests.
+type SearchResult struct {
+	Number   int
+	Title    string
+	MergedAt time.Time
+}
+
+// SearchPullRequests resolves a search query, e.g. "is:merged
+// label:needs-backport base:main", to the merged PRs of the repository, in
+// the order they were merged. Open and closed PRs are skipped.
+func SearchPullRequests(ctx context.Context, repo gitobj.Repository, query string) ([]SearchResult, error) {
+	var results []SearchResult
+	err := tui.WithStep(ctx, "searching pull requests", func(ctx context.Context, logger log.Logger) error {
+		q := fmt.Sprintf("repo:%s is:pr %s", repo.NameWithOwner(), query)
+
+		logger.WithField("query", q).Infof("searching")
+		endpoint := fmt.Sprintf("search/issues?q=%s&per_page=%d", url.QueryEscape(q), searchPageSize)
+		output, err := ghAPIQueryAll(ctx, repo.Host, endpoint, `.total_count, (.items[] | [.number, .pull_request.merged_at // "", .title] | @tsv)`)
+		if err != nil {
+			return fmt.Errorf("error searching pull requests: %w", err)
+		}
+
+		total, found, skipped, err := parseSearchResults(output)
+		if err != nil {
+			return err
+		}
+		// The oldest PRs may be among those which are not returned.
+		if total > maxSearchResults {
+			return fmt.Errorf("%d PRs match %q, more than the %d the search returns. narrow down the query", total, query, maxSearchResults)
+		}
+		for _, number := range skipped {
+			logger.WithField("pr", number).Warnf("skipping the PR, which is not merged")
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("no merged PR matches %q", query)
+		}
+
+		results = found
+		for _, r := range results {
+			logger.Successf("%s %s %s", color.Cyan(fmt.Sprintf("#%d", r.Number)), r.Title, color.Grey(r.MergedAt.Format(time.DateOnly)))
+		}
+		return nil
+	})
+	return results, err
+}
+
+// parseSearchResults parses the pages of the search, each of the total count
+// followed by a line of number, merge time and title for each PR. The merged
+// PRs are sorted in the order they were merged, and the numbers of the
+// others are skipped.
+func parseSearchResults(output string) (total int, results []SearchResult, skipped []int, err error) {
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 1 {
+			if total, err = strconv.Atoi(line); err != nil {
+				return 0, nil, nil, fmt.Errorf("unexpected search total %q: %w", line, err)
+			}
+			continue
+		} else if len(fields) != 3 {
+			continue
+		}
+		number, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("unexpected PR number %q: %w", fields[0], err)
+		}
+		if fields[1] == "" {
+			skipped = append(skipped, number)
+			continue
+		}
+		mergedAt, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("unexpected merge time %q of #%d: %w", fields[1], number, err)
+		}
+		results = append(results, SearchResult{Number: number, Title: fields[2], MergedAt: mergedAt})
+	}
+
+	slices.SortStableFunc(results, func(a, b SearchResult) int { return cmp.Compare(a.MergedAt.Unix(), b.MergedAt.Unix()) })
+	return total, results, skipped, nil
+}
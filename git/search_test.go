@@ -0,0 +1,37 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSearchResults(t *testing.T) {
+	// Two pages of the search.
+	output := "3\n" +
+		"12\t2026-09-03T10:00:00Z\tFix the parser\n" +
+		"15\t\tDraft the docs\n" +
+		"3\n" +
+		"9\t2026-09-01T08:30:00Z\tBump the deps (again)\n"
+
+	total, results, skipped, err := parseSearchResults(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected a total of 3, got %d", total)
+	}
+
+	var numbers []int
+	for _, r := range results {
+		numbers = append(numbers, r.Number)
+	}
+	if expected := []int{9, 12}; !slices.Equal(numbers, expected) {
+		t.Errorf("expected %v in merge order, got %v", expected, numbers)
+	}
+	if results[0].Title != "Bump the deps (again)" {
+		t.Errorf("unexpected title %q", results[0].Title)
+	}
+	if expected := []int{15}; !slices.Equal(skipped, expected) {
+		t.Errorf("expected %v skipped, got %v", expected, skipped)
+	}
+}
@@ -0,0 +1,105 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const stateFileName = "gh-cherry-pick-state.json"
+
+// State is kept in the git directory while a cherry-pick branch is checked
+// out, so that an unfinished cherry-pick can be aborted later.
+type State struct {
+	// OriginalBranch is the branch checked out before the cherry-pick. It is
+	// empty when HEAD was detached.
+	OriginalBranch string `json:"originalBranch,omitempty"`
+	OriginalHead   string `json:"originalHead"`
+	// Branch is the cherry-pick branch.
+	Branch string `json:"branch"`
+	// StashRef is the stash commit holding the local changes stashed with -autostash.
+	StashRef string `json:"stashRef,omitempty"`
+}
+
+// NewState captures the currently checked out branch and HEAD.
+func NewState(ctx context.Context, branch string) (*State, error) {
+	head, err := RevParse(ctx, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	originalBranch, err := GetCurrentBranch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the current branch: %w", err)
+	}
+
+	return &State{OriginalBranch: originalBranch, OriginalHead: head, Branch: branch}, nil
+}
+
+// LoadState returns the saved state, or nil when there is none.
+func LoadState(ctx context.Context) (*State, error) {
+	path, err := statePath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var state State
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+func (s *State) Save(ctx context.Context) error {
+	path, err := statePath(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the state: %w", err)
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// OriginalRef is what to switch back to when leaving the cherry-pick branch.
+func (s *State) OriginalRef() string {
+	if s.OriginalBranch != "" {
+		return s.OriginalBranch
+	}
+	return s.OriginalHead
+}
+
+func RemoveState(ctx context.Context) error {
+	path, err := statePath(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+func statePath(ctx context.Context) (string, error) {
+	gitDir, err := GetGitDir(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the git directory: %w", err)
+	}
+	return filepath.Join(gitDir, stateFileName), nil
+}
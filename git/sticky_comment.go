@@ -0,0 +1,107 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+// stickyCommentMarker identifies the comment listing the backports of a PR,
+// so that every run edits the same comment.
+const stickyCommentMarker = "<!-- gh-cherry-pick:backports -->"
+
+// backportStatus is a row of the sticky comment.
+type backportStatus struct {
+	Target string
+	Status string
+}
+
+func renderStickyComment(statuses []backportStatus) string {
+	var sb strings.Builder
+	sb.WriteString(stickyCommentMarker + "\n")
+	sb.WriteString("### 🍒 Backports\n\n")
+	sb.WriteString("| Target | Status |\n")
+	sb.WriteString("|--------|--------|\n")
+	for _, s := range statuses {
+		fmt.Fprintf(&sb, "| `%s` | %s |\n", s.Target, s.Status)
+	}
+	return sb.String()
+}
+
+// parseStickyComment returns the rows of a comment rendered by renderStickyComment.
+func parseStickyComment(body string) []backportStatus {
+	var statuses []backportStatus
+	for _, line := range strings.Split(body, "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "| `")
+		if !ok {
+			continue
+		}
+		target, status, ok := strings.Cut(rest, "` | ")
+		if !ok {
+			continue
+		}
+		statuses = append(statuses, backportStatus{Target: target, Status: strings.TrimSuffix(status, " |")})
+	}
+	return statuses
+}
+
+// setStatus replaces the row of the target, or appends it.
+func setStatus(statuses []backportStatus, status backportStatus) []backportStatus {
+	for i, s := range statuses {
+		if s.Target == status.Target {
+			statuses[i] = status
+			return statuses
+		}
+	}
+	return append(statuses, status)
+}
+
+// UpdateStickyComment sets the status of the target in the backports comment
+// of the PR, creating the comment on first use.
+func UpdateStickyComment(ctx context.Context, repo gitobj.Repository, prNumber int, target, status string) error {
+	// Every page is searched, as a busy PR has more comments than fit in one.
+	endpoint := fmt.Sprintf("repos/%s/issues/%d/comments?per_page=100", repo.NameWithOwner(), prNumber)
+	jq := fmt.Sprintf(`.[] | select(.body | contains(%q)) | {id, body} | @json`, stickyCommentMarker)
+	output, err := ghAPIQueryAll(ctx, repo.Host, endpoint, jq)
+	if err != nil {
+		return fmt.Errorf("error finding the backports comment: %w", err)
+	}
+	found, err := parseStickyCommentLookup(output)
+	if err != nil {
+		return fmt.Errorf("error finding the backports comment: %w", err)
+	}
+
+	row := backportStatus{Target: target, Status: strings.ReplaceAll(status, "|", "\\|")}
+	if found == nil {
+		body := renderStickyComment([]backportStatus{row})
+		_, err = ghAPIRequest(ctx, repo.Host, "POST", fmt.Sprintf("repos/%s/issues/%d/comments", repo.NameWithOwner(), prNumber), map[string]string{"body": body})
+		return err
+	}
+
+	body := renderStickyComment(setStatus(parseStickyComment(found.Body), row))
+	_, err = ghAPIRequest(ctx, repo.Host, "PATCH", fmt.Sprintf("repos/%s/issues/comments/%d", repo.NameWithOwner(), found.ID), map[string]string{"body": body})
+	return err
+}
+
+// stickyComment is a comment which has the marker.
+type stickyComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// parseStickyCommentLookup returns the first of the comments with the
+// marker, one JSON object per line, or nil when there is none.
+func parseStickyCommentLookup(output string) (*stickyComment, error) {
+	if output == "" {
+		return nil, nil
+	}
+	line, _, _ := strings.Cut(output, "\n")
+	var comment stickyComment
+	if err := json.Unmarshal([]byte(line), &comment); err != nil {
+		return nil, fmt.Errorf("unexpected comment %q: %w", line, err)
+	}
+	return &comment, nil
+}
@@ -0,0 +1,81 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStickyCommentStatuses(t *testing.T) {
+	existing := renderStickyComment([]backportStatus{
+		{Target: "release/1.1", Status: "✅ https://github.com/owner/repo/pull/13"},
+		{Target: "release/1.2", Status: "⚠️ conflicts in `go.mod`"},
+	})
+
+	testcases := []struct {
+		name     string
+		status   backportStatus
+		expected []backportStatus
+	}{{
+		name:   "new target",
+		status: backportStatus{Target: "release/1.3", Status: "☑️ already present"},
+		expected: []backportStatus{
+			{Target: "release/1.1", Status: "✅ https://github.com/owner/repo/pull/13"},
+			{Target: "release/1.2", Status: "⚠️ conflicts in `go.mod`"},
+			{Target: "release/1.3", Status: "☑️ already present"},
+		},
+	}, {
+		name:   "rerun target",
+		status: backportStatus{Target: "release/1.2", Status: "✅ https://github.com/owner/repo/pull/14"},
+		expected: []backportStatus{
+			{Target: "release/1.1", Status: "✅ https://github.com/owner/repo/pull/13"},
+			{Target: "release/1.2", Status: "✅ https://github.com/owner/repo/pull/14"},
+		},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			statuses := setStatus(parseStickyComment(existing), tc.status)
+			if !reflect.DeepEqual(statuses, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, statuses)
+			}
+			if reparsed := parseStickyComment(renderStickyComment(statuses)); !reflect.DeepEqual(reparsed, tc.expected) {
+				t.Errorf("expected %v after rendering, got %v", tc.expected, reparsed)
+			}
+		})
+	}
+}
+
+func TestParseStickyCommentLookup(t *testing.T) {
+	testcases := []struct {
+		name     string
+		output   string
+		expected *stickyComment
+		wantErr  bool
+	}{{
+		name: "no comment",
+	}, {
+		name:     "comment on a later page",
+		output:   `{"body":"<!-- gh-cherry-pick:backports -->\n| ` + "`release/1.2`" + ` | ✅ |\n","id":42}`,
+		expected: &stickyComment{ID: 42, Body: "<!-- gh-cherry-pick:backports -->\n| `release/1.2` | ✅ |\n"},
+	}, {
+		name:     "the first of several",
+		output:   "{\"body\":\"first\",\"id\":1}\n{\"body\":\"second\",\"id\":2}",
+		expected: &stickyComment{ID: 1, Body: "first"},
+	}, {
+		name:    "invalid",
+		output:  "1\nbody",
+		wantErr: true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseStickyCommentLookup(tc.output)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
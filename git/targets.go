@@ -0,0 +1,332 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+const (
+	// releaseBranchesConfigKey is the glob of the release branches which
+	// latest:N picks from, e.g. git config gh-cherry-pick.releaseBranches 'release/*'.
+	releaseBranchesConfigKey = "gh-cherry-pick.releaseBranches"
+	// supportedConfigKey is the multi-valued git config of the still
+	// supported lines, as branches or globs, e.g.
+	// git config --add gh-cherry-pick.supported 'release/1.*'.
+	supportedConfigKey = "gh-cherry-pick.supported"
+
+	defaultReleaseBranches = "release/*"
+)
+
+// ResolveTargets resolves the comma-separated target specs of -onto into the
+// branches of origin. A spec is a branch, a glob like release/*, latest:N for
+// the newest N release branches, or supported for the lines of the
+// gh-cherry-pick.supported git config. The branches of the patterns are
+// sorted newest first, by the version in their names. expanded reports
+// whether any spec is a pattern.
+func ResolveTargets(ctx context.Context, onto string) (targets []string, expanded bool, err error) {
+	specs := SplitList(onto)
+	if len(specs) == 0 {
+		return nil, false, fmt.Errorf("no target branch given")
+	}
+	if !slices.ContainsFunc(specs, isTargetPattern) {
+		return specs, false, nil
+	}
+
+	err = tui.WithStep(ctx, "resolving the target branches", func(ctx context.Context, logger log.Logger) error {
+		logger.Infof("listing the branches of origin")
+		branches, err := remoteBranches(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, spec := range specs {
+			matched, err := matchTargets(ctx, spec, branches)
+			if err != nil {
+				return err
+			}
+			if len(matched) == 0 {
+				return fmt.Errorf("no branch of origin matches %s", spec)
+			}
+			for _, branch := range matched {
+				if !slices.Contains(targets, branch) {
+					targets = append(targets, branch)
+				}
+			}
+		}
+
+		for _, target := range targets {
+			logger.Successf("%s", color.Cyan(target))
+		}
+		return nil
+	})
+	return targets, true, err
+}
+
+// isTargetPattern reports whether a target spec selects several branches.
+func isTargetPattern(spec string) bool {
+	return strings.ContainsAny(spec, "*?[") || strings.HasPrefix(spec, "latest:") || spec == "supported"
+}
+
+// matchTargets returns the branches which a target spec selects.
+func matchTargets(ctx context.Context, spec string, branches []string) ([]string, error) {
+	if n, ok := strings.CutPrefix(spec, "latest:"); ok {
+		count, err := strconv.Atoi(n)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid target %q: expected latest:N with a positive N", spec)
+		}
+
+		glob := GetConfig(ctx, releaseBranchesConfigKey)
+		if glob == "" {
+			glob = defaultReleaseBranches
+		}
+		releases := slices.DeleteFunc(matchBranches(branches, glob), func(branch string) bool { return versionOf(branch) == nil })
+		return releases[:min(count, len(releases))], nil
+	}
+
+	if spec == "supported" {
+		globs := GetConfigAll(ctx, supportedConfigKey)
+		if len(globs) == 0 {
+			return nil, fmt.Errorf("no supported lines configured. add them with %s", color.Yellow(fmt.Sprintf("`git config --add %s <branch or glob>`", supportedConfigKey)))
+		}
+
+		var supported []string
+		for _, glob := range globs {
+			for _, branch := range matchBranches(branches, glob) {
+				if !slices.Contains(supported, branch) {
+					supported = append(supported, branch)
+				}
+			}
+		}
+		sortNewestFirst(supported)
+		return supported, nil
+	}
+
+	if strings.ContainsAny(spec, "*?[") {
+		if _, err := filepath.Match(spec, ""); err != nil {
+			return nil, fmt.Errorf("invalid target glob %q: %w", spec, err)
+		}
+		return matchBranches(branches, spec), nil
+	}
+	return []string{spec}, nil
+}
+
+// matchBranches returns the branches which match the glob, newest first.
+func matchBranches(branches []string, glob string) []string {
+	var matched []string
+	for _, branch := range branches {
+		if ok, _ := filepath.Match(glob, branch); ok {
+			matched = append(matched, branch)
+		}
+	}
+	sortNewestFirst(matched)
+	return matched
+}
+
+// sortNewestFirst sorts branches by the version in their names, newest
+// first. Branches without one, like the default branch, come first.
+func sortNewestFirst(branches []string) {
+	slices.SortStableFunc(branches, func(a, b string) int {
+		va, vb := versionOf(a), versionOf(b)
+		switch {
+		case va == nil && vb == nil:
+			return 0
+		case va == nil:
+			return -1
+		case vb == nil:
+			return 1
+		}
+		return slices.Compare(vb, va)
+	})
+}
+
+// maxSuggestions bounds the branches suggested for a missing target branch.
+const maxSuggestions = 3
+
+// checkTargetExists verifies that the target branch exists on origin, and
+// suggests the closest branches when it does not.
+func checkTargetExists(ctx context.Context, target string) error {
+	branches, err := remoteBranches(ctx)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(branches, target) {
+		return nil
+	}
+
+	if commitSHAPattern.MatchString(target) {
+		return fmt.Errorf("%s is not a branch. pass %s to create the target branch from the commit", target, color.Yellow("-create-branch <branch>"))
+	}
+
+	suggestions := closestBranches(branches, target, maxSuggestions)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("the target branch %s does not exist on origin", target)
+	}
+	for i, suggestion := range suggestions {
+		suggestions[i] = color.Cyan(suggestion)
+	}
+	return fmt.Errorf("the target branch %s does not exist on origin. did you mean %s?", target, strings.Join(suggestions, ", "))
+}
+
+// prepareTarget resolves StartPoint when the target branch does not exist on
+// origin. The branch is only created by createTarget, once the cherry-pick
+// branch is pushed, so that a failed cherry-pick leaves nothing behind.
+func (cherryPick *CherryPick) prepareTarget(ctx context.Context, logger log.Logger) error {
+	branches, err := remoteBranches(ctx)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(branches, cherryPick.OnTo) {
+		logger.Warnf("%s exists already. it is used as is instead of creating it from %s", color.Cyan(cherryPick.OnTo), color.Cyan(cherryPick.StartPoint))
+		return nil
+	}
+
+	logger.WithField("ref", cherryPick.StartPoint).Infof("resolving the start point")
+	if cherryPick.startCommit, err = resolveStartPoint(ctx, cherryPick.StartPoint); err != nil {
+		return err
+	}
+	logger.Successf("%s will be created from %s %s", color.Cyan(cherryPick.OnTo), color.Cyan(cherryPick.StartPoint), color.Grey(cherryPick.startCommit[:7]))
+	return nil
+}
+
+// createTarget creates the target branch on origin from the resolved start
+// point.
+func (cherryPick *CherryPick) createTarget(ctx context.Context, logger log.Logger) error {
+	logger.WithField("branch", cherryPick.OnTo).WithField("from", cherryPick.startCommit[:7]).Infof("creating the target branch")
+	if err := NewCommand("git", "push", "origin", cherryPick.startCommit+":refs/heads/"+cherryPick.OnTo).Run(ctx); err != nil {
+		return fmt.Errorf("error creating the branch %s on origin: %w", cherryPick.OnTo, err)
+	}
+	logger.Successf("created %s from %s", color.Cyan(cherryPick.OnTo), color.Cyan(cherryPick.StartPoint))
+	return nil
+}
+
+// targetRef is what the cherry-pick branch starts from: the target branch of
+// origin, or the start point of the target branch to create.
+func (cherryPick *CherryPick) targetRef() string {
+	if cherryPick.startCommit != "" {
+		return cherryPick.startCommit
+	}
+	return "origin/" + cherryPick.OnTo
+}
+
+// fetchTarget fetches the target branch, unless it is still to be created.
+func (cherryPick *CherryPick) fetchTarget(ctx context.Context, logger log.Logger) error {
+	if cherryPick.startCommit != "" {
+		return nil
+	}
+
+	logger.WithField("branch", cherryPick.OnTo).Infof("fetching the branch")
+	if err := Fetch(ctx, "origin", cherryPick.OnTo); err != nil {
+		return fmt.Errorf("error fetching the branch '%s': %w", cherryPick.OnTo, err)
+	}
+	return nil
+}
+
+// resolveStartPoint returns the commit of a tag, branch or commit SHA,
+// fetching it from origin unless it is a commit which is here already.
+func resolveStartPoint(ctx context.Context, ref string) (string, error) {
+	if commitSHAPattern.MatchString(ref) {
+		if commit, err := RevParse(ctx, ref+"^{commit}"); err == nil {
+			return commit, nil
+		}
+	}
+
+	if err := Fetch(ctx, "origin", ref); err != nil {
+		return "", fmt.Errorf("error fetching %s. a commit needs its full SHA: %w", ref, err)
+	}
+	commit, err := RevParse(ctx, "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s to a commit: %w", ref, err)
+	}
+	return commit, nil
+}
+
+// closestBranches returns up to n branches closest to target by edit
+// distance, closest first. Branches which differ in more than half of target
+// are left out.
+func closestBranches(branches []string, target string, n int) []string {
+	type candidate struct {
+		branch   string
+		distance int
+	}
+	var candidates []candidate
+	for _, branch := range branches {
+		if distance := editDistance(branch, target); distance <= max(len(target)/2, 1) {
+			candidates = append(candidates, candidate{branch: branch, distance: distance})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return a.distance - b.distance })
+
+	closest := make([]string, 0, n)
+	for _, c := range candidates[:min(n, len(candidates))] {
+		closest = append(closest, c.branch)
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// checkNotContained fetches the target branch, and verifies that it does
+// not contain the merge commit of the PR already, e.g. when it was branched
+// off after the PR was merged.
+func (cherryPick *CherryPick) checkNotContained(ctx context.Context, logger log.Logger, pr *gitobj.PullRequest) error {
+	if err := cherryPick.fetchTarget(ctx, logger); err != nil {
+		return err
+	}
+
+	err := NewCommand("git", "merge-base", "--is-ancestor", pr.MergeCommit.Sha, cherryPick.targetRef()).Run(ctx)
+	if err == nil {
+		return fmt.Errorf("%s contains the merge commit %s of %s already. nothing to cherry-pick", cherryPick.OnTo, pr.MergeCommit.Sha[:7], pr.PRNumberString())
+	}
+	var gitError *GitError
+	if errors.As(err, &gitError) && gitError.ExitCode == 1 {
+		return nil
+	}
+	return fmt.Errorf("error checking if %s contains the merge commit %s: %w", cherryPick.OnTo, pr.MergeCommit.Sha[:7], err)
+}
+
+// remoteBranches lists the branches of origin with git ls-remote.
+func remoteBranches(ctx context.Context) ([]string, error) {
+	stdout := &bytes.Buffer{}
+	if err := NewCommand("git", "ls-remote", "--heads", "origin").Run(ctx, WithStdout(stdout)); err != nil {
+		return nil, fmt.Errorf("error listing the branches of origin: %w", err)
+	}
+
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if _, ref, ok := strings.Cut(line, "\t"); ok {
+			branches = append(branches, strings.TrimPrefix(ref, "refs/heads/"))
+		}
+	}
+	return branches, nil
+}
@@ -0,0 +1,131 @@
+package git
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+func TestMatchBranches(t *testing.T) {
+	branches := []string{"main", "release/1.9", "release/1.10", "release/2.0", "release/1.10-hotfix/foo", "feature/x"}
+
+	testcases := []struct {
+		glob     string
+		expected []string
+	}{
+		{glob: "release/*", expected: []string{"release/2.0", "release/1.10", "release/1.9"}},
+		{glob: "release/1.*", expected: []string{"release/1.10", "release/1.9"}},
+		{glob: "main", expected: []string{"main"}},
+		{glob: "hotfix/*", expected: nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.glob, func(t *testing.T) {
+			if actual := matchBranches(branches, tc.glob); !slices.Equal(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestClosestBranches(t *testing.T) {
+	branches := []string{"main", "release/1.2", "release/1.3", "release/2.2", "develop"}
+
+	testcases := []struct {
+		target   string
+		expected []string
+	}{
+		{target: "relase/1.2", expected: []string{"release/1.2", "release/1.3", "release/2.2"}},
+		{target: "mian", expected: []string{"main"}},
+		{target: "production", expected: []string{}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.target, func(t *testing.T) {
+			if actual := closestBranches(branches, tc.target, 3); !slices.Equal(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+// newTestRemote creates a repository as newTestRepo does, whose origin has
+// main, release and the tag v1.0 of the first commit, which is not here.
+func newTestRemote(t *testing.T) (context.Context, func(script string) string) {
+	t.Helper()
+	ctx, sh := newTestRepo(t)
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	sh("git init -q --bare " + origin + " && git remote add origin " + origin +
+		" && git tag v1.0 main~1 && git push -q origin main release v1.0 && git tag -d v1.0")
+	return ctx, sh
+}
+
+func TestResolveStartPoint(t *testing.T) {
+	ctx, sh := newTestRemote(t)
+
+	testcases := []struct {
+		ref      string
+		expected string
+		wantErr  bool
+	}{
+		{ref: "v1.0", expected: sh("git rev-parse main~1")},
+		{ref: "release", expected: sh("git rev-parse release")},
+		{ref: sh("git rev-parse feature"), expected: sh("git rev-parse feature")},
+		{ref: "v9.9", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.ref, func(t *testing.T) {
+			actual, err := resolveStartPoint(ctx, tc.ref)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPrepareTarget(t *testing.T) {
+	ctx, sh := newTestRemote(t)
+	logger := log.NewLogger()
+
+	testcases := []struct {
+		name     string
+		onto     string
+		expected string
+	}{
+		{name: "existing branch", onto: "release"},
+		{name: "new branch", onto: "hotfix/1.0", expected: sh("git rev-parse main~1")},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cherryPick := CherryPick{OnTo: tc.onto, StartPoint: "v1.0"}
+			if err := cherryPick.prepareTarget(ctx, logger); err != nil {
+				t.Fatal(err)
+			}
+			if cherryPick.startCommit != tc.expected {
+				t.Fatalf("expected the start commit %q, got %q", tc.expected, cherryPick.startCommit)
+			}
+			if tc.expected == "" {
+				return
+			}
+
+			// Nothing is created on origin until the cherry-pick branch is pushed.
+			if branches, err := remoteBranches(ctx); err != nil || slices.Contains(branches, tc.onto) {
+				t.Fatalf("expected no %s on origin yet, got %v, %v", tc.onto, branches, err)
+			}
+			if err := cherryPick.createTarget(ctx, logger); err != nil {
+				t.Fatal(err)
+			}
+			if created := sh("git ls-remote origin refs/heads/" + tc.onto); created != tc.expected+"\trefs/heads/"+tc.onto {
+				t.Errorf("expected %s on origin at %s, got %q", tc.onto, tc.expected, created)
+			}
+		})
+	}
+}
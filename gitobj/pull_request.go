@@ -26,8 +26,60 @@ type PullRequest struct {
 	MergeCommit struct {
 		Sha string `json:"oid"`
 	} `json:"mergeCommit"`
-	BaseRefName string `json:"baseRefName"`
-	HeadRefName string `json:"headRefName"`
+	BaseRefName string   `json:"baseRefName"`
+	HeadRefName string   `json:"headRefName"`
+	Commits     []Commit `json:"commits"`
+
+	// The metadata below is only fetched to copy it to a cherry-pick PR.
+	Labels         []Label         `json:"labels"`
+	Assignees      []User          `json:"assignees"`
+	ReviewRequests []ReviewRequest `json:"reviewRequests"`
+	LatestReviews  []Review        `json:"latestReviews"`
+	Milestone      *Milestone      `json:"milestone"`
+	ProjectItems   []ProjectItem   `json:"projectItems"`
+}
+
+type Commit struct {
+	Oid             string `json:"oid"`
+	MessageHeadline string `json:"messageHeadline"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type User struct {
+	Login string `json:"login"`
+}
+
+// ReviewRequest is a pending review request from a user or a team.
+type ReviewRequest struct {
+	Login        string `json:"login"`
+	Slug         string `json:"slug"`
+	Organization struct {
+		Login string `json:"login"`
+	} `json:"organization"`
+}
+
+// Reviewer returns the user login, or ORG/TEAM for a team.
+func (r ReviewRequest) Reviewer() string {
+	if r.Slug != "" && r.Organization.Login != "" {
+		return r.Organization.Login + "/" + r.Slug
+	}
+	return r.Login
+}
+
+type Review struct {
+	Author User `json:"author"`
+}
+
+type Milestone struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+}
+
+type ProjectItem struct {
+	Title string `json:"title"`
 }
 
 func (pr PullRequest) StateString() string {
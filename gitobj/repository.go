@@ -0,0 +1,34 @@
+package gitobj
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Repository struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+func (r Repository) NameWithOwner() string {
+	return fmt.Sprintf("%s/%s", r.Owner, r.Name)
+}
+
+// String returns the repository in the [HOST/]OWNER/REPO form accepted by the --repo flag of gh.
+func (r Repository) String() string {
+	if r.Host == "" {
+		return r.NameWithOwner()
+	}
+	return fmt.Sprintf("%s/%s", r.Host, r.NameWithOwner())
+}
+
+func (r Repository) CloneURL() string {
+	return fmt.Sprintf("https://%s/%s.git", r.Host, r.NameWithOwner())
+}
+
+func (r Repository) Equal(other Repository) bool {
+	return strings.EqualFold(r.Host, other.Host) &&
+		strings.EqualFold(r.Owner, other.Owner) &&
+		strings.EqualFold(r.Name, other.Name)
+}
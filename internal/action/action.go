@@ -0,0 +1,87 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/internal/bot"
+	"github.com/134130/gh-cherry-pick/internal/githubapp"
+	"github.com/134130/gh-cherry-pick/internal/log"
+	"github.com/134130/gh-cherry-pick/internal/tui"
+)
+
+const (
+	botName  = "github-actions[bot]"
+	botEmail = "41898282+github-actions[bot]@users.noreply.github.com"
+)
+
+type Options struct {
+	// LabelPrefix marks the labels which request a backport, e.g. "backport release/1.2".
+	LabelPrefix   string
+	MergeStrategy git.MergeStrategy
+	OnConflict    git.ConflictAction
+	// App authenticates as a GitHub App installation instead of GITHUB_TOKEN.
+	App *githubapp.App
+}
+
+// Run handles the GitHub Actions event. A merged PR is backported onto the
+// branches named by its labels, and a /backport comment onto the branches
+// it names, creating a pull request for each. Conflicts are reported with a
+// comment on the PR, and only other failures fail the run.
+func Run(ctx context.Context, opts Options) error {
+	env, err := loadEnvironment()
+	if err != nil {
+		return err
+	}
+	if err = env.exportToken(); err != nil {
+		return fmt.Errorf("error exporting the token: %w", err)
+	}
+
+	event, err := env.loadEvent(opts.LabelPrefix)
+	if err != nil {
+		return err
+	}
+	if event == nil {
+		log.LoggerFromCtx(ctx).Infof("no backport requested by the %s event", env.eventName)
+		return nil
+	}
+
+	if opts.App != nil {
+		installation, err := opts.App.InstallationFor(ctx, env.repo, event.InstallationID)
+		if err != nil {
+			return err
+		}
+		ctx = git.CtxWithAuth(ctx, installation)
+	}
+
+	if err = configureGit(ctx); err != nil {
+		return err
+	}
+
+	results, err := bot.HandleEvent(ctx, event, bot.Options{
+		MergeStrategy: opts.MergeStrategy,
+		Metadata:      bot.DefaultMetadata(opts.LabelPrefix),
+		OnConflict:    opts.OnConflict,
+	})
+	if len(results) == 0 {
+		return err
+	}
+	return report(ctx, env, event.PRNumber, results, err)
+}
+
+// configureGit sets the identity of the commits when the workflow has not.
+func configureGit(ctx context.Context) error {
+	return tui.WithStep(ctx, "configuring git", func(ctx context.Context, logger log.Logger) error {
+		for key, value := range map[string]string{"user.name": botName, "user.email": botEmail} {
+			if git.GetConfig(ctx, key) != "" {
+				continue
+			}
+			logger.WithField(key, value).Infof("setting git identity")
+			if err := git.SetConfig(ctx, key, value); err != nil {
+				return fmt.Errorf("error setting %s: %w", key, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+package action
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/bot"
+)
+
+// environment is what GitHub Actions passes to a step.
+type environment struct {
+	eventName   string
+	eventPath   string
+	repo        gitobj.Repository
+	token       string
+	stepSummary string
+}
+
+func loadEnvironment() (*environment, error) {
+	env := &environment{
+		eventName:   os.Getenv("GITHUB_EVENT_NAME"),
+		eventPath:   os.Getenv("GITHUB_EVENT_PATH"),
+		token:       os.Getenv("GITHUB_TOKEN"),
+		stepSummary: os.Getenv("GITHUB_STEP_SUMMARY"),
+	}
+
+	for name, value := range map[string]string{
+		"GITHUB_EVENT_NAME": env.eventName,
+		"GITHUB_EVENT_PATH": env.eventPath,
+		"GITHUB_TOKEN":      env.token,
+	} {
+		if value == "" {
+			return nil, fmt.Errorf("%s is not set. is this running in GitHub Actions?", name)
+		}
+	}
+
+	owner, name, ok := strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/")
+	if !ok {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY is not set or invalid: %q", os.Getenv("GITHUB_REPOSITORY"))
+	}
+
+	host := "github.com"
+	if serverURL := os.Getenv("GITHUB_SERVER_URL"); serverURL != "" {
+		u, err := url.Parse(serverURL)
+		if err != nil {
+			return nil, fmt.Errorf("GITHUB_SERVER_URL is invalid: %w", err)
+		}
+		host = u.Host
+	}
+
+	env.repo = gitobj.Repository{Host: host, Owner: owner, Name: name}
+	return env, nil
+}
+
+// loadEvent parses the event payload of the workflow, or returns nil when it
+// requests no backport.
+func (env *environment) loadEvent(labelPrefix string) (*bot.Event, error) {
+	payload, err := os.ReadFile(env.eventPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the event payload: %w", err)
+	}
+
+	event, err := bot.ParseEvent(env.eventName, payload, labelPrefix)
+	if err != nil || event == nil {
+		return nil, err
+	}
+	// The workflow token pushes to the repository of the workflow.
+	event.Repo = env.repo
+	return event, nil
+}
+
+// exportToken makes gh use the token of the workflow.
+func (env *environment) exportToken() error {
+	tokenVar := "GH_TOKEN"
+	if env.repo.Host != "github.com" {
+		tokenVar = "GH_ENTERPRISE_TOKEN"
+		if err := os.Setenv("GH_HOST", env.repo.Host); err != nil {
+			return err
+		}
+	}
+
+	if os.Getenv(tokenVar) != "" {
+		return nil
+	}
+	return os.Setenv(tokenVar, env.token)
+}
@@ -0,0 +1,77 @@
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadEvent(t *testing.T) {
+	const labels = `[{"name": "bug"}, {"name": "backport release/1.2"}, {"name": "backport release/1.1"}]`
+
+	testcases := []struct {
+		name      string
+		event     string
+		payload   string
+		serverURL string
+		expected  []string
+		host      string
+	}{{
+		name:     "closed and merged",
+		event:    "pull_request",
+		payload:  `{"action": "closed", "pull_request": {"number": 12, "merged": true, "labels": ` + labels + `}}`,
+		expected: []string{"release/1.2", "release/1.1"},
+		host:     "github.com",
+	}, {
+		name:      "labeled after merge on GitHub Enterprise Server",
+		event:     "pull_request_target",
+		payload:   `{"action": "labeled", "label": {"name": "backport release/1.1"}, "pull_request": {"number": 12, "merged": true, "labels": ` + labels + `}}`,
+		serverURL: "https://github.example.com",
+		expected:  []string{"release/1.1"},
+		host:      "github.example.com",
+	}, {
+		name:    "labeled with another label",
+		event:   "pull_request",
+		payload: `{"action": "labeled", "label": {"name": "bug"}, "pull_request": {"number": 12, "merged": true, "labels": ` + labels + `}}`,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			eventPath := filepath.Join(t.TempDir(), "event.json")
+			if err := os.WriteFile(eventPath, []byte(tc.payload), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("GITHUB_EVENT_NAME", tc.event)
+			t.Setenv("GITHUB_EVENT_PATH", eventPath)
+			t.Setenv("GITHUB_TOKEN", "token")
+			t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+			t.Setenv("GITHUB_SERVER_URL", tc.serverURL)
+
+			env, err := loadEnvironment()
+			if err != nil {
+				t.Fatal(err)
+			}
+			event, err := env.loadEvent("backport ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expected == nil {
+				if event != nil {
+					t.Errorf("expected no event, got %+v", event)
+				}
+				return
+			}
+			if event == nil {
+				t.Fatalf("expected targets %v, got no event", tc.expected)
+			}
+			if !reflect.DeepEqual(event.Targets, tc.expected) {
+				t.Errorf("expected targets %v, got %v", tc.expected, event.Targets)
+			}
+			if expected := tc.host + "/owner/repo"; event.Repo.String() != expected {
+				t.Errorf("expected repository %s, got %s", expected, event.Repo)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/internal/bot"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// report writes the step summary and annotations of the results. It returns
+// err, or an error when any target failed other than by conflicting.
+func report(ctx context.Context, env *environment, prNumber int, results []bot.Result, err error) error {
+	logger := log.LoggerFromCtx(ctx)
+
+	summary := bot.ResultsMarkdown(fmt.Sprintf("Backports of #%d", prNumber), prNumber, results)
+	if summaryErr := appendStepSummary(env.stepSummary, summary); summaryErr != nil {
+		logger.WithError(summaryErr).Warnf("error writing the step summary")
+	}
+
+	var failures int
+	for _, r := range results {
+		switch {
+		case r.Conflicted:
+			annotate("warning", fmt.Sprintf("Backport to %s conflicts", r.Target), r.Status())
+		case r.Err != nil:
+			failures++
+			annotate("error", fmt.Sprintf("Backport to %s failed", r.Target), r.Err.Error())
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d backport(s) failed", failures, len(results))
+	}
+	return nil
+}
+
+func appendStepSummary(path, markdown string) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(markdown + "\n")
+	return err
+}
+
+// annotate prints a workflow command which GitHub Actions shows as an annotation.
+func annotate(level, title, message string) {
+	message = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(message)
+	_, _ = fmt.Fprintf(os.Stdout, "::%s title=%s::%s\n", level, title, message)
+}
@@ -0,0 +1,63 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/134130/gh-cherry-pick/internal/bot"
+)
+
+func TestReport(t *testing.T) {
+	testcases := []struct {
+		name    string
+		results []bot.Result
+		// expected are the lines the step summary must have.
+		expected []string
+		wantErr  bool
+	}{{
+		name: "created and conflicted",
+		results: []bot.Result{
+			{Target: "release/1.2", PullRequestURL: "https://github.com/owner/repo/pull/13"},
+			{Target: "release/1.1", Conflicted: true, ConflictedFiles: []string{"a.go"}},
+		},
+		expected: []string{
+			"### 🍒 Backports of #12",
+			"| `release/1.2` | ✅ https://github.com/owner/repo/pull/13 |",
+			"| `release/1.1` | ⚠️ conflicts in `a.go` |",
+			"gh cherry-pick -pr 12 -onto release/1.1",
+		},
+	}, {
+		name: "failed",
+		results: []bot.Result{
+			{Target: "release/1.2", Err: errors.New("error pushing\nmore details")},
+		},
+		expected: []string{"| `release/1.2` | ❌ error pushing |"},
+		wantErr:  true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := &environment{stepSummary: filepath.Join(t.TempDir(), "summary.md")}
+			err := report(context.Background(), env, 12, tc.results, nil)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			summary, err := os.ReadFile(env.stepSummary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(string(summary), "\n")
+			for _, line := range tc.expected {
+				if !slices.Contains(lines, line) {
+					t.Errorf("expected the line %q in the summary\n%s", line, summary)
+				}
+			}
+		})
+	}
+}
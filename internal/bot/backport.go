@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/color"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+type Options struct {
+	MergeStrategy git.MergeStrategy
+	// Metadata selects what the backport PRs get from the original PR.
+	Metadata git.MetadataOptions
+	// OnConflict is what to do when a backport conflicts.
+	OnConflict git.ConflictAction
+}
+
+// DefaultMetadata copies the metadata of the original PR to the backport PRs,
+// except for the labels requesting backports, and assigns its author.
+func DefaultMetadata(labelPrefix string) git.MetadataOptions {
+	return git.MetadataOptions{Copy: true, DropLabels: []string{labelPrefix + "*"}, AssignAuthor: true}
+}
+
+// Result is the outcome of backporting a PR to one target branch.
+type Result struct {
+	PRNumber       int
+	Target         string
+	PullRequestURL string
+	AlreadyPresent bool
+	// Draft is set when the conflicts in ConflictedFiles are pushed as a draft PR.
+	Draft           bool
+	Conflicted      bool
+	ConflictedFiles []string
+	Err             error
+}
+
+// Backport cherry-picks a PR onto target and creates a pull request for it.
+// A conflicting cherry-pick is aborted after recording the conflicting files.
+func Backport(ctx context.Context, repo gitobj.Repository, prNumber int, target string, opts Options) Result {
+	logger := log.LoggerFromCtx(ctx)
+	logger.Infof("🍒 %s", color.Bold(fmt.Sprintf("backporting #%d onto %s\n", prNumber, target)))
+
+	// The targets of a /backport command are given by anyone who may push.
+	if err := git.CheckBranchName(ctx, target); err != nil {
+		logger.Failf(err.Error())
+		return Result{PRNumber: prNumber, Target: target, Err: err}
+	}
+
+	cherryPick := git.CherryPick{
+		PRNumber:      prNumber,
+		Repo:          &repo,
+		OnTo:          target,
+		MergeStrategy: opts.MergeStrategy,
+		CreatePR:      true,
+		Metadata:      opts.Metadata,
+		OnConflict:    opts.OnConflict,
+		RecordOrigin:  true,
+	}
+
+	r := Result{PRNumber: prNumber, Target: target}
+	if r.Err = cherryPick.RunWithContext(ctx); r.Err == nil {
+		r.PullRequestURL = cherryPick.Result.PullRequestURL
+		r.AlreadyPresent = cherryPick.Result.AlreadyPresent
+		r.Draft, r.ConflictedFiles = cherryPick.Result.Draft, cherryPick.Result.ConflictedFiles
+		return r
+	}
+
+	logger.Failf(r.Err.Error())
+
+	var conflictErr *git.ConflictError
+	if r.Conflicted = errors.As(r.Err, &conflictErr); r.Conflicted {
+		r.ConflictedFiles, _ = git.ConflictedFiles(ctx)
+		if err := git.Abort(ctx); err != nil {
+			logger.WithError(err).Warnf("error aborting the conflicted cherry-pick")
+		}
+	}
+	return r
+}
@@ -0,0 +1,28 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+)
+
+const backportCommand = "/backport"
+
+// ParseCommand returns the target branches of the /backport commands in a
+// comment, e.g. "/backport release/1.2 release/1.3". Each command must be on
+// its own line; quoted lines are ignored.
+func ParseCommand(body string) []string {
+	var targets []string
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Fields(strings.ReplaceAll(line, ",", " "))
+		if len(fields) == 0 || fields[0] != backportCommand {
+			continue
+		}
+
+		for _, target := range fields[1:] {
+			if !slices.Contains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	testcases := []struct {
+		name     string
+		body     string
+		expected []string
+	}{{
+		name:     "single target",
+		body:     "/backport release/1.2",
+		expected: []string{"release/1.2"},
+	}, {
+		name:     "multiple targets and lines",
+		body:     "LGTM\n/backport release/1.2, release/1.3\r\n/backport release/1.2 release/1.1",
+		expected: []string{"release/1.2", "release/1.3", "release/1.1"},
+	}, {
+		name: "quoted command",
+		body: "> /backport release/1.2",
+	}, {
+		name: "command in a sentence",
+		body: "please /backport release/1.2",
+	}, {
+		name: "other command",
+		body: "/backports release/1.2",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if targets := ParseCommand(tc.body); !reflect.DeepEqual(targets, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, targets)
+			}
+		})
+	}
+}
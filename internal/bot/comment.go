@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// backportPermissions are the permissions allowed to run /backport.
+var backportPermissions = []string{"admin", "write"}
+
+// checkPermission returns an error, after replying so, when the commenter
+// may not push to the repository.
+func checkPermission(ctx context.Context, event *Event) error {
+	user := event.Comment.User
+	permission, err := git.GetPermission(ctx, event.Repo, user)
+	if err != nil {
+		return fmt.Errorf("error getting the permission of %s: %w", user, err)
+	}
+	if slices.Contains(backportPermissions, permission) {
+		return nil
+	}
+
+	react(ctx, event.Repo, event.Comment.ID, "-1")
+	reply := fmt.Sprintf("@%s you need write permission on this repository to run `%s`.", user, backportCommand)
+	if err = git.CommentOnPullRequest(ctx, event.Repo, event.PRNumber, reply); err != nil {
+		return fmt.Errorf("error replying to %s: %w", user, err)
+	}
+	return fmt.Errorf("%s has %s permission, which is not allowed to run %s", user, permission, backportCommand)
+}
+
+func replyToComment(ctx context.Context, event *Event, results []Result) error {
+	user := event.Comment.User
+	reply := fmt.Sprintf("@%s\n\n%s", user, ResultsMarkdown("Backport results", event.PRNumber, results))
+	if err := git.CommentOnPullRequest(ctx, event.Repo, event.PRNumber, reply); err != nil {
+		return fmt.Errorf("error replying to %s: %w", user, err)
+	}
+
+	if slices.ContainsFunc(results, func(r Result) bool { return r.Err != nil }) {
+		react(ctx, event.Repo, event.Comment.ID, "confused")
+	} else {
+		react(ctx, event.Repo, event.Comment.ID, "rocket")
+	}
+	return nil
+}
+
+// react adds a reaction to a comment. Reactions are informational, so errors are only logged.
+func react(ctx context.Context, repo gitobj.Repository, commentID int64, content string) {
+	if err := git.AddReaction(ctx, repo, commentID, content); err != nil {
+		log.LoggerFromCtx(ctx).WithError(err).Warnf("error reacting with %s", content)
+	}
+}
@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// Event is a webhook event which requests backports of a PR.
+type Event struct {
+	Name     string            `json:"name"`
+	Repo     gitobj.Repository `json:"repo"`
+	PRNumber int               `json:"prNumber"`
+	Targets  []string          `json:"targets"`
+	// Comment is the comment with the /backport command. It is nil when the
+	// backports are requested by labels.
+	Comment *Comment `json:"comment,omitempty"`
+	// InstallationID is the GitHub App installation the event was delivered
+	// to, if any.
+	InstallationID int64 `json:"installationId,omitempty"`
+}
+
+type Comment struct {
+	ID   int64  `json:"id"`
+	User string `json:"user"`
+}
+
+type label struct {
+	Name string `json:"name"`
+}
+
+type repository struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	HTMLURL string `json:"html_url"`
+}
+
+type installation struct {
+	ID int64 `json:"id"`
+}
+
+type pullRequestPayload struct {
+	Action      string `json:"action"`
+	Label       *label `json:"label"`
+	PullRequest struct {
+		Number int     `json:"number"`
+		Merged bool    `json:"merged"`
+		Labels []label `json:"labels"`
+	} `json:"pull_request"`
+	Repository   repository    `json:"repository"`
+	Installation *installation `json:"installation"`
+}
+
+type issueCommentPayload struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"comment"`
+	Repository   repository    `json:"repository"`
+	Installation *installation `json:"installation"`
+}
+
+// ParseEvent parses a webhook payload, as delivered to a webhook or read
+// from GITHUB_EVENT_PATH in GitHub Actions. It returns nil when the event
+// requests no backport.
+//
+// A merged pull_request event requests a backport onto every branch named
+// by its labels starting with labelPrefix, or only onto the branch of the
+// label just added. An issue_comment event requests backports with
+// /backport commands in a new PR comment.
+func ParseEvent(name string, payload []byte, labelPrefix string) (*Event, error) {
+	var event *Event
+	var repo repository
+	var inst *installation
+
+	switch name {
+	case "pull_request", "pull_request_target":
+		var p pullRequestPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, fmt.Errorf("error parsing the %s event payload: %w", name, err)
+		}
+		event, repo, inst = &Event{Name: name, PRNumber: p.PullRequest.Number, Targets: labelTargets(p, labelPrefix)}, p.Repository, p.Installation
+
+	case "issue_comment":
+		var p issueCommentPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, fmt.Errorf("error parsing the %s event payload: %w", name, err)
+		}
+		if p.Action != "created" || p.Issue.PullRequest == nil {
+			return nil, nil
+		}
+		event, repo, inst = &Event{
+			Name:     name,
+			PRNumber: p.Issue.Number,
+			Targets:  ParseCommand(p.Comment.Body),
+			Comment:  &Comment{ID: p.Comment.ID, User: p.Comment.User.Login},
+		}, p.Repository, p.Installation
+
+	default:
+		return nil, nil
+	}
+
+	if len(event.Targets) == 0 {
+		return nil, nil
+	}
+
+	u, err := url.Parse(repo.HTMLURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository URL %q: %w", repo.HTMLURL, err)
+	}
+	event.Repo = gitobj.Repository{Host: u.Host, Owner: repo.Owner.Login, Name: repo.Name}
+	if inst != nil {
+		event.InstallationID = inst.ID
+	}
+	return event, nil
+}
+
+func labelTargets(p pullRequestPayload, labelPrefix string) []string {
+	if !p.PullRequest.Merged {
+		return nil
+	}
+
+	var labels []label
+	switch p.Action {
+	case "closed":
+		labels = p.PullRequest.Labels
+	case "labeled":
+		if p.Label != nil {
+			labels = []label{*p.Label}
+		}
+	}
+
+	var targets []string
+	for _, l := range labels {
+		if target, ok := strings.CutPrefix(l.Name, labelPrefix); ok && strings.TrimSpace(target) != "" {
+			targets = append(targets, strings.TrimSpace(target))
+		}
+	}
+	return targets
+}
+
+// HandleEvent backports the PR of the event onto each target in the current
+// directory, which must be a clone of the repository of the event.
+//
+// For /backport commands, it checks that the commenter may push, reacts to
+// the comment and replies with the results. For labels, it comments on the
+// PR only when a backport conflicts.
+func HandleEvent(ctx context.Context, event *Event, opts Options) ([]Result, error) {
+	logger := log.LoggerFromCtx(ctx)
+
+	if event.Comment != nil {
+		logger.WithField("user", event.Comment.User).Infof("#%d: %s %s", event.PRNumber, backportCommand, strings.Join(event.Targets, " "))
+		if err := checkPermission(ctx, event); err != nil {
+			return nil, err
+		}
+		react(ctx, event.Repo, event.Comment.ID, "eyes")
+	} else {
+		logger.Infof("#%d: backport onto %s", event.PRNumber, strings.Join(event.Targets, " "))
+	}
+
+	results := make([]Result, 0, len(event.Targets))
+	for _, target := range event.Targets {
+		results = append(results, Backport(ctx, event.Repo, event.PRNumber, target, opts))
+	}
+
+	if event.Comment != nil {
+		return results, replyToComment(ctx, event, results)
+	}
+
+	if slices.ContainsFunc(results, func(r Result) bool { return r.Conflicted }) {
+		comment := ResultsMarkdown("Backport stopped on conflicts", event.PRNumber, results)
+		if err := git.CommentOnPullRequest(ctx, event.Repo, event.PRNumber, comment); err != nil {
+			return results, fmt.Errorf("error commenting on #%d: %w", event.PRNumber, err)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	pullRequest := func(action string, merged bool, label string) []byte {
+		p := map[string]any{
+			"action": action,
+			"pull_request": map[string]any{
+				"number": 12,
+				"merged": merged,
+				"labels": []map[string]string{{"name": "bug"}, {"name": "backport release/1.2"}, {"name": "backport release/1.1"}},
+			},
+			"repository": map[string]any{"name": "repo", "owner": map[string]string{"login": "owner"}, "html_url": "https://github.com/owner/repo"},
+		}
+		if label != "" {
+			p["label"] = map[string]string{"name": label}
+		}
+		data, _ := json.Marshal(p)
+		return data
+	}
+	issueComment := func(action, body string) []byte {
+		p := map[string]any{
+			"action":     action,
+			"issue":      map[string]any{"number": 12, "pull_request": map[string]any{}},
+			"comment":    map[string]any{"id": 34, "body": body, "user": map[string]string{"login": "octocat"}},
+			"repository": map[string]any{"name": "repo", "owner": map[string]string{"login": "owner"}, "html_url": "https://github.com/owner/repo"},
+		}
+		data, _ := json.Marshal(p)
+		return data
+	}
+
+	testcases := []struct {
+		name     string
+		event    string
+		payload  []byte
+		expected []string
+		comment  bool
+	}{{
+		name:     "closed and merged",
+		event:    "pull_request",
+		payload:  pullRequest("closed", true, ""),
+		expected: []string{"release/1.2", "release/1.1"},
+	}, {
+		name:    "closed without merge",
+		event:   "pull_request",
+		payload: pullRequest("closed", false, ""),
+	}, {
+		name:     "labeled after merge",
+		event:    "pull_request_target",
+		payload:  pullRequest("labeled", true, "backport release/1.1"),
+		expected: []string{"release/1.1"},
+	}, {
+		name:    "labeled with another label",
+		event:   "pull_request",
+		payload: pullRequest("labeled", true, "bug"),
+	}, {
+		name:     "backport command",
+		event:    "issue_comment",
+		payload:  issueComment("created", "/backport release/1.2"),
+		expected: []string{"release/1.2"},
+		comment:  true,
+	}, {
+		name:    "edited comment",
+		event:   "issue_comment",
+		payload: issueComment("edited", "/backport release/1.2"),
+	}, {
+		name:    "unsupported event",
+		event:   "push",
+		payload: []byte(`{}`),
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			event, err := ParseEvent(tc.event, tc.payload, "backport ")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expected == nil {
+				if event != nil {
+					t.Fatalf("expected no event, got %+v", event)
+				}
+				return
+			}
+			if event == nil {
+				t.Fatalf("expected targets %v, got no event", tc.expected)
+			}
+			if event.PRNumber != 12 {
+				t.Errorf("expected PR #12, got #%d", event.PRNumber)
+			}
+			if event.Repo.String() != "github.com/owner/repo" {
+				t.Errorf("expected repository github.com/owner/repo, got %s", event.Repo)
+			}
+			if !reflect.DeepEqual(event.Targets, tc.expected) {
+				t.Errorf("expected targets %v, got %v", tc.expected, event.Targets)
+			}
+			if (event.Comment != nil) != tc.comment {
+				t.Errorf("expected comment %v, got %+v", tc.comment, event.Comment)
+			}
+		})
+	}
+}
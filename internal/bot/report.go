@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/134130/gh-cherry-pick/git"
+)
+
+func (r Result) Status() string {
+	return git.Outcome{
+		PullRequestURL:  r.PullRequestURL,
+		AlreadyPresent:  r.AlreadyPresent,
+		Draft:           r.Draft,
+		Conflicted:      r.Conflicted,
+		ConflictedFiles: r.ConflictedFiles,
+		Err:             r.Err,
+	}.Status()
+}
+
+// ResultsMarkdown renders the results as a table, followed by the commands
+// to backport the conflicting targets manually.
+func ResultsMarkdown(title string, prNumber int, results []Result) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "### 🍒 %s\n\n", title)
+	sb.WriteString("| Target | Status |\n")
+	sb.WriteString("|--------|--------|\n")
+	for _, r := range results {
+		fmt.Fprintf(&sb, "| `%s` | %s |\n", r.Target, r.Status())
+	}
+
+	var conflicted []Result
+	for _, r := range results {
+		if r.Conflicted {
+			conflicted = append(conflicted, r)
+		}
+	}
+	if len(conflicted) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString("\nTo backport manually, resolve the conflicts after running:\n\n```shell\n")
+	for _, r := range conflicted {
+		fmt.Fprintf(&sb, "gh cherry-pick -pr %d -onto %s\n", prNumber, r.Target)
+	}
+	sb.WriteString("```\n")
+	return sb.String()
+}
@@ -0,0 +1,223 @@
+package githubapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+// refreshBefore is how long before its expiry an installation token is
+// replaced, so that a token never expires in the middle of a backport.
+const refreshBefore = 5 * time.Minute
+
+// App authenticates as a GitHub App, whose installations authenticate gh and
+// git with installation tokens.
+type App struct {
+	id     int64
+	key    *rsa.PrivateKey
+	host   string
+	apiURL string
+	client *http.Client
+
+	mu            sync.Mutex
+	installations map[int64]*Installation
+	identity      *identity
+}
+
+// New returns the GitHub App with the ID and PEM private key on host.
+func New(host string, id int64, privateKey []byte) (*App, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		host = "github.com"
+	}
+	return &App{id: id, key: key, host: host, apiURL: apiURL(host), client: http.DefaultClient, installations: map[int64]*Installation{}}, nil
+}
+
+// Load returns the GitHub App with the ID and the private key file
+// downloaded from its settings.
+func Load(host string, id int64, privateKeyPath string) (*App, error) {
+	privateKey, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the private key of the GitHub App: %w", err)
+	}
+	return New(host, id, privateKey)
+}
+
+func apiURL(host string) string {
+	if host == "github.com" {
+		return "https://api.github.com"
+	}
+	return fmt.Sprintf("https://%s/api/v3", host)
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("the private key of the GitHub App is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the private key of the GitHub App: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key of the GitHub App is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// jwt returns the JSON Web Token which authenticates as the app itself. It
+// is backdated a minute against clock drift, and valid for less than the
+// maximum of ten minutes.
+func (a *App) jwt(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprint(a.id),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(nil, a.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("error signing the JWT of the GitHub App: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// InstallationID returns the installation of the app on the repository.
+func (a *App) InstallationID(ctx context.Context, repo gitobj.Repository) (int64, error) {
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	endpoint := fmt.Sprintf("repos/%s/%s/installation", url.PathEscape(repo.Owner), url.PathEscape(repo.Name))
+	if err := a.request(ctx, http.MethodGet, endpoint, "", &installation); err != nil {
+		return 0, fmt.Errorf("error getting the installation of the GitHub App on %s: %w", repo.NameWithOwner(), err)
+	}
+	return installation.ID, nil
+}
+
+// Installation returns the installation with the ID. Its tokens are shared by
+// every caller.
+func (a *App) Installation(id int64) *Installation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if installation, ok := a.installations[id]; ok {
+		return installation
+	}
+	installation := &Installation{app: a, id: id}
+	a.installations[id] = installation
+	return installation
+}
+
+type identity struct {
+	name  string
+	email string
+}
+
+// botIdentity returns the git identity of the bot user of the app, e.g.
+// "my-app[bot] <123+my-app[bot]@users.noreply.github.com>", which GitHub
+// links to the app. It is looked up once; after an error, it is empty.
+func (a *App) botIdentity(ctx context.Context, token string) (identity, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.identity != nil {
+		return *a.identity, nil
+	}
+	a.identity = &identity{}
+
+	var app struct {
+		Slug string `json:"slug"`
+	}
+	if err := a.request(ctx, http.MethodGet, "app", "", &app); err != nil {
+		return identity{}, fmt.Errorf("error getting the GitHub App: %w", err)
+	}
+
+	name := app.Slug + "[bot]"
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := a.request(ctx, http.MethodGet, "users/"+url.PathEscape(name), token, &user); err != nil {
+		return identity{}, fmt.Errorf("error getting the bot user of the GitHub App: %w", err)
+	}
+
+	a.identity = &identity{name: name, email: fmt.Sprintf("%d+%s@users.noreply.%s", user.ID, name, a.host)}
+	return *a.identity, nil
+}
+
+// request calls the REST API and decodes the response into v. It
+// authenticates with token, or as the app when token is empty.
+func (a *App) request(ctx context.Context, method, endpoint, token string, v any) error {
+	authorization := "token " + token
+	if token == "" {
+		jwt, err := a.jwt(time.Now())
+		if err != nil {
+			return err
+		}
+		authorization = "Bearer " + jwt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.apiURL+"/"+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, endpoint, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// InstallationFor returns the installation with the ID, or the installation
+// on the repository when the ID is 0.
+func (a *App) InstallationFor(ctx context.Context, repo gitobj.Repository, id int64) (*Installation, error) {
+	if id == 0 {
+		var err error
+		if id, err = a.InstallationID(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+	return a.Installation(id), nil
+}
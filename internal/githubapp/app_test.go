@@ -0,0 +1,146 @@
+package githubapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/gitobj"
+)
+
+// fakeGitHub serves the endpoints of the app API, creating tokens which
+// expire after expiresIn.
+func fakeGitHub(t *testing.T, key *rsa.PrivateKey, expiresIn time.Duration, created *atomic.Int32) *httptest.Server {
+	verifyJWT := func(r *http.Request) bool {
+		jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return false
+		}
+		parts := strings.Split(jwt, ".")
+		if len(parts) != 3 {
+			return false
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return false
+		}
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature) != nil {
+			return false
+		}
+
+		var claims struct {
+			Iss string `json:"iss"`
+			Exp int64  `json:"exp"`
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		return json.Unmarshal(payload, &claims) == nil && claims.Iss == "42" && claims.Exp > time.Now().Unix()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/owner/repo/installation", func(w http.ResponseWriter, r *http.Request) {
+		if !verifyJWT(r) {
+			http.Error(w, "bad JWT", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"id": 7}`)
+	})
+	mux.HandleFunc("POST /app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		if !verifyJWT(r) {
+			http.Error(w, "bad JWT", http.StatusUnauthorized)
+			return
+		}
+		n := created.Add(1)
+		fmt.Fprintf(w, `{"token": "token-%d", "expires_at": %q}`, n, time.Now().Add(expiresIn).Format(time.RFC3339))
+	})
+	mux.HandleFunc("GET /app", func(w http.ResponseWriter, r *http.Request) {
+		if !verifyJWT(r) {
+			http.Error(w, "bad JWT", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"slug": "backporter"}`)
+	})
+	mux.HandleFunc("GET /users/backporter[bot]", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1234}`)
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestApp(t *testing.T, expiresIn time.Duration, created *atomic.Int32) *App {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	app, err := New("github.com", 42, pemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.apiURL = fakeGitHub(t, key, expiresIn, created).URL
+	return app
+}
+
+func TestInstallationToken(t *testing.T) {
+	testcases := []struct {
+		name      string
+		expiresIn time.Duration
+		expected  []string
+	}{{
+		name:      "reused while valid",
+		expiresIn: time.Hour,
+		expected:  []string{"token-1", "token-1"},
+	}, {
+		name:      "refreshed before expiry",
+		expiresIn: refreshBefore - time.Minute,
+		expected:  []string{"token-1", "token-2"},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var created atomic.Int32
+			app := newTestApp(t, tc.expiresIn, &created)
+
+			ctx := context.Background()
+			for i, expected := range tc.expected {
+				installation, err := app.InstallationFor(ctx, gitobj.Repository{Host: "github.com", Owner: "owner", Name: "repo"}, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				token, err := installation.Token(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if token != expected {
+					t.Errorf("call %d: expected %s, got %s", i+1, expected, token)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallationIdentity(t *testing.T) {
+	var created atomic.Int32
+	app := newTestApp(t, time.Hour, &created)
+
+	name, email := app.Installation(7).Identity(context.Background())
+	if name != "backporter[bot]" || email != "1234+backporter[bot]@users.noreply.github.com" {
+		t.Errorf("unexpected identity %s <%s>", name, email)
+	}
+}
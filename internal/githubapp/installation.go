@@ -0,0 +1,64 @@
+package githubapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+var _ git.Auth = (*Installation)(nil)
+
+// Installation authenticates gh and git as the bot user of an installation
+// of the app.
+type Installation struct {
+	app *App
+	id  int64
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// Token returns the installation token, creating a new one when it expires
+// within refreshBefore.
+func (i *Installation) Token(ctx context.Context) (string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.token != "" && time.Until(i.expiresAt) > refreshBefore {
+		return i.token, nil
+	}
+
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	endpoint := fmt.Sprintf("app/installations/%d/access_tokens", i.id)
+	if err := i.app.request(ctx, http.MethodPost, endpoint, "", &token); err != nil {
+		return "", fmt.Errorf("error creating a token for installation %d of the GitHub App: %w", i.id, err)
+	}
+
+	log.LoggerFromCtx(ctx).WithField("expires", token.ExpiresAt.Format(time.RFC3339)).Infof("created a token for installation %d of the GitHub App", i.id)
+	i.token, i.expiresAt = token.Token, token.ExpiresAt
+	return i.token, nil
+}
+
+// Identity returns the bot user of the app. When it cannot be looked up, the
+// configured git identity is kept.
+func (i *Installation) Identity(ctx context.Context) (name, email string) {
+	token, err := i.Token(ctx)
+	if err != nil {
+		return "", ""
+	}
+	identity, err := i.app.botIdentity(ctx, token)
+	if err != nil {
+		log.LoggerFromCtx(ctx).WithError(err).Warnf("error getting the bot identity of the GitHub App")
+		return "", ""
+	}
+	return identity.name, identity.email
+}
@@ -2,7 +2,6 @@ package log
 
 import (
 	"fmt"
-	"os"
 	"slices"
 	"strings"
 
@@ -108,7 +107,7 @@ func (e *entry) print(icon, msg string) {
 
 	output := lipgloss.JoinHorizontal(lipgloss.Top, bullet, content)
 	if len(e.fields) == 0 {
-		_, _ = fmt.Fprintln(os.Stdout, output)
+		_, _ = fmt.Fprintln(e.logger.stdout(), output)
 		return
 	}
 
@@ -117,7 +116,7 @@ func (e *entry) print(icon, msg string) {
 		fields = append(fields, fmt.Sprintf("%s=%v", color.Purple(f.key), f.value))
 	}
 
-	_, _ = fmt.Fprintln(os.Stderr, lipgloss.JoinHorizontal(
+	_, _ = fmt.Fprintln(e.logger.stderr(), lipgloss.JoinHorizontal(
 		lipgloss.Top,
 		output,
 		lipgloss.NewStyle().PaddingLeft(max(maxIndent-lipgloss.Width(output), 0)).Render(strings.Join(fields, " "))),
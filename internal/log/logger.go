@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/charmbracelet/lipgloss"
@@ -21,12 +22,31 @@ func NewLogger() Logger {
 	return &logger{}
 }
 
+// NewLoggerTo returns a logger which writes to w instead of stdout and
+// stderr, e.g. to keep the output of a cherry-pick running in the background.
+func NewLoggerTo(w io.Writer) Logger {
+	return &logger{out: w}
+}
+
 var loggerKey = struct{}{}
 
 func CtxWithLogger(ctx context.Context) context.Context {
 	return context.WithValue(ctx, loggerKey, NewLogger())
 }
 
+// CtxWithLoggerTo returns ctx with a logger which writes to w.
+func CtxWithLoggerTo(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, loggerKey, NewLoggerTo(w))
+}
+
+// WriterFromCtx returns where the logger of ctx writes its messages to.
+func WriterFromCtx(ctx context.Context) io.Writer {
+	if l, ok := ctx.Value(loggerKey).(*logger); ok {
+		return l.stdout()
+	}
+	return os.Stdout
+}
+
 func LoggerFromCtx(ctx context.Context) Logger {
 	l, ok := ctx.Value(loggerKey).(Logger)
 	if !ok {
@@ -39,6 +59,22 @@ var _ Logger = (*logger)(nil)
 
 type logger struct {
 	Indent int
+	// out replaces stdout and stderr when set.
+	out io.Writer
+}
+
+func (l *logger) stdout() io.Writer {
+	if l.out != nil {
+		return l.out
+	}
+	return os.Stdout
+}
+
+func (l *logger) stderr() io.Writer {
+	if l.out != nil {
+		return l.out
+	}
+	return os.Stderr
 }
 
 func (l *logger) WithField(s string, i interface{}) Logger {
@@ -97,5 +133,5 @@ func (l *logger) print(icon, msg string) {
 	bullet := lipgloss.NewStyle().PaddingLeft(1 + l.Indent).Render(icon)
 	content := lipgloss.NewStyle().PaddingLeft(1).Render(msg)
 
-	_, _ = fmt.Fprintln(os.Stdout, lipgloss.JoinHorizontal(lipgloss.Top, bullet, content))
+	_, _ = fmt.Fprintln(l.stdout(), lipgloss.JoinHorizontal(lipgloss.Top, bullet, content))
 }
@@ -23,3 +23,24 @@ func (o *OnceValue[T]) Do(ctx context.Context, f func(context.Context) (T, error
 
 	return o.value, o.err
 }
+
+// OnceValues is like OnceValue, but remembers a value per key.
+type OnceValues[K comparable, T any] struct {
+	mu     sync.Mutex
+	values map[K]*OnceValue[T]
+}
+
+func (o *OnceValues[K, T]) Do(ctx context.Context, key K, f func(context.Context) (T, error)) (T, error) {
+	o.mu.Lock()
+	if o.values == nil {
+		o.values = make(map[K]*OnceValue[T])
+	}
+	value, ok := o.values[key]
+	if !ok {
+		value = &OnceValue[T]{}
+		o.values[key] = value
+	}
+	o.mu.Unlock()
+
+	return value.Do(ctx, f)
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/internal/bot"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is a webhook event waiting for, or done with, its backports.
+type Job struct {
+	ID string `json:"id"`
+	// Delivery is the X-GitHub-Delivery of the webhook, so that a redelivery
+	// does not queue the same backports twice.
+	Delivery  string      `json:"delivery,omitempty"`
+	Event     bot.Event   `json:"event"`
+	Status    JobStatus   `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Results   []JobResult `json:"results,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+type JobResult struct {
+	Target string `json:"target"`
+	Status string `json:"status"`
+}
+
+func newJobID(now time.Time) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%s", now.UTC().Format("20060102T150405"), hex.EncodeToString(b))
+}
+
+// jobStore is a queue of jobs persisted as one JSON file per job, so that the
+// jobs queued or running when the server stops are run again on restart.
+type jobStore struct {
+	dir string
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   map[string]*Job
+	queue  []string
+	closed bool
+}
+
+func openJobStore(dir string) (*jobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating the job directory: %w", err)
+	}
+
+	s := &jobStore{dir: dir, jobs: map[string]*Job{}}
+	s.cond = sync.NewCond(&s.mu)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading job %s: %w", file, err)
+		}
+		var job Job
+		if err = json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("error parsing job %s: %w", file, err)
+		}
+		s.jobs[job.ID] = &job
+	}
+
+	for _, job := range s.sorted() {
+		if job.Status == JobQueued || job.Status == JobRunning {
+			job.Status = JobQueued
+			s.queue = append(s.queue, job.ID)
+		}
+	}
+	return s, nil
+}
+
+// add queues a job for the event. It returns the existing job instead when
+// the delivery was already received.
+func (s *jobStore) add(delivery string, event bot.Event) (job *Job, created bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if delivery != "" {
+		for _, job := range s.jobs {
+			if job.Delivery == delivery {
+				return copyJob(job), false, nil
+			}
+		}
+	}
+
+	now := time.Now()
+	job = &Job{ID: newJobID(now), Delivery: delivery, Event: event, Status: JobQueued, CreatedAt: now, UpdatedAt: now}
+	if err = s.save(job); err != nil {
+		return nil, false, err
+	}
+	s.jobs[job.ID] = job
+	s.queue = append(s.queue, job.ID)
+	s.cond.Signal()
+	return copyJob(job), true, nil
+}
+
+// next blocks until a job is queued and marks it running. It returns nil
+// once the store is closed.
+func (s *jobStore) next() (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.queue) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return nil, nil
+	}
+
+	job := s.jobs[s.queue[0]]
+	s.queue = s.queue[1:]
+	job.Status = JobRunning
+	job.UpdatedAt = time.Now()
+	return copyJob(job), s.save(job)
+}
+
+// finish records the outcome of a job.
+func (s *jobStore) finish(id string, status JobStatus, results []JobResult, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.jobs[id]
+	job.Status = status
+	job.Results = results
+	job.Error = ""
+	if err != nil {
+		job.Error = err.Error()
+	}
+	job.UpdatedAt = time.Now()
+	return s.save(job)
+}
+
+// close wakes up the workers waiting for a job. The queued jobs stay on disk.
+func (s *jobStore) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.cond.Broadcast()
+}
+
+// list returns the jobs, newest first.
+func (s *jobStore) list() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sorted := s.sorted()
+	jobs := make([]Job, 0, len(sorted))
+	for i := len(sorted) - 1; i >= 0; i-- {
+		jobs = append(jobs, *copyJob(sorted[i]))
+	}
+	return jobs
+}
+
+func (s *jobStore) sorted() []*Job {
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	slices.SortFunc(jobs, func(a, b *Job) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return jobs
+}
+
+// save writes the job through a temporary file, so that a crash never leaves
+// a truncated job behind.
+func (s *jobStore) save(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, job.ID+".json")
+	if err = os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("error saving job %s: %w", job.ID, err)
+	}
+	if err = os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("error saving job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+func copyJob(job *Job) *Job {
+	c := *job
+	c.Event.Targets = slices.Clone(job.Event.Targets)
+	c.Results = slices.Clone(job.Results)
+	return &c
+}
@@ -0,0 +1,37 @@
+package server
+
+import "html/template"
+
+var jobsPage = template.Must(template.New("jobs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="10">
+<title>gh-cherry-pick jobs</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.4em 0.8em; text-align: left; vertical-align: top; }
+.queued, .running { color: #9a6700; }
+.succeeded { color: #1a7f37; }
+.failed { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>🍒 Jobs</h1>
+{{if not .}}<p>No jobs yet.</p>{{else}}
+<table>
+<tr><th>Job</th><th>Pull request</th><th>Targets</th><th>Status</th><th>Results</th><th>Updated</th></tr>
+{{range .}}<tr>
+<td>{{.ID}}</td>
+<td><a href="https://{{.Event.Repo.Host}}/{{.Event.Repo.NameWithOwner}}/pull/{{.Event.PRNumber}}">{{.Event.Repo.NameWithOwner}}#{{.Event.PRNumber}}</a></td>
+<td>{{range .Event.Targets}}<code>{{.}}</code> {{end}}</td>
+<td class="{{.Status}}">{{.Status}}{{with .Error}}<br>{{.}}{{end}}</td>
+<td>{{range .Results}}<code>{{.Target}}</code>: {{.Status}}<br>{{end}}</td>
+<td>{{.UpdatedAt.Format "2006-01-02 15:04:05 MST"}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/internal/bot"
+	"github.com/134130/gh-cherry-pick/internal/githubapp"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// maxPayloadSize is the largest webhook payload GitHub delivers.
+const maxPayloadSize = 25 << 20
+
+type Config struct {
+	// Addr is the address to listen on, e.g. ":8080".
+	Addr string
+	// Secret is the secret of the webhook, which signs every delivery.
+	Secret string
+	// DataDir keeps the job queue, the clones and the worktrees of the jobs.
+	DataDir string
+	// Workers is the number of jobs run at the same time.
+	Workers int
+	// LabelPrefix marks the labels which request a backport, e.g. "backport release/1.2".
+	LabelPrefix   string
+	MergeStrategy git.MergeStrategy
+	OnConflict    git.ConflictAction
+	// App authenticates as a GitHub App installation instead of the gh user.
+	App *githubapp.App
+}
+
+// processor runs the backports of a job.
+type processor func(ctx context.Context, job *Job) ([]bot.Result, error)
+
+// Server receives GitHub webhooks and backports the PRs they request in a
+// pool of workers, each in its own worktree.
+type Server struct {
+	config  Config
+	jobs    *jobStore
+	process processor
+	logger  log.Logger
+
+	reposMu sync.Mutex
+	repos   map[string]*sync.Mutex
+}
+
+func New(config Config) (*Server, error) {
+	if config.Secret == "" {
+		return nil, errors.New("the webhook secret is required")
+	}
+	if config.Workers < 1 {
+		return nil, fmt.Errorf("the number of workers must be positive, got %d", config.Workers)
+	}
+
+	jobs, err := openJobStore(filepath.Join(config.DataDir, "jobs"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{config: config, jobs: jobs, logger: log.NewLogger(), repos: map[string]*sync.Mutex{}}
+	s.process = s.runInWorktree
+	return s, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhook", s.handleWebhook)
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /jobs", s.handleJobs)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/jobs", http.StatusFound)
+	})
+	return mux
+}
+
+// Run serves the webhook and runs the queued jobs until ctx is done. The jobs
+// interrupted by then are queued again on the next start.
+func (s *Server) Run(ctx context.Context) error {
+	wait := s.start(ctx)
+	defer wait()
+
+	server := &http.Server{Addr: s.config.Addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.WithField("workers", s.config.Workers).Infof("listening on %s", s.config.Addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// start starts the workers, and returns a function which waits for them to
+// stop after ctx is done.
+func (s *Server) start(ctx context.Context) (wait func()) {
+	var wg sync.WaitGroup
+	for range s.config.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+
+	stop := context.AfterFunc(ctx, s.jobs.close)
+	return func() {
+		wg.Wait()
+		stop()
+	}
+}
+
+func (s *Server) work(ctx context.Context) {
+	for {
+		job, err := s.jobs.next()
+		if err != nil {
+			s.logger.WithError(err).Warnf("error marking job running")
+		}
+		if job == nil {
+			return
+		}
+		s.runJob(ctx, job)
+	}
+}
+
+func (s *Server) runJob(ctx context.Context, job *Job) {
+	logger := s.logger.WithField("job", job.ID)
+	logger.Infof("running backports of %s#%d onto %v", job.Event.Repo.NameWithOwner(), job.Event.PRNumber, job.Event.Targets)
+
+	results, err := s.authenticateAndProcess(log.CtxWithLogger(ctx), job)
+
+	status := JobSucceeded
+	jobResults := make([]JobResult, 0, len(results))
+	for _, r := range results {
+		jobResults = append(jobResults, JobResult{Target: r.Target, Status: r.Status()})
+		if r.Err != nil && !r.Conflicted {
+			status = JobFailed
+		}
+	}
+	if err != nil {
+		status = JobFailed
+	}
+	if ctx.Err() != nil {
+		// Interrupted by the shutdown, so run it again on restart.
+		status, err = JobQueued, nil
+	}
+
+	if saveErr := s.jobs.finish(job.ID, status, jobResults, err); saveErr != nil {
+		logger.WithError(saveErr).Warnf("error saving the job")
+	}
+	if status == JobFailed {
+		logger.WithError(err).Failf("job failed")
+	} else {
+		logger.Successf("job %s", status)
+	}
+}
+
+func (s *Server) authenticateAndProcess(ctx context.Context, job *Job) ([]bot.Result, error) {
+	if s.config.App != nil {
+		installation, err := s.config.App.InstallationFor(ctx, job.Event.Repo, job.Event.InstallationID)
+		if err != nil {
+			return nil, err
+		}
+		ctx = git.CtxWithAuth(ctx, installation)
+	}
+	return s.process(ctx, job)
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "error reading the payload", http.StatusBadRequest)
+		return
+	}
+	if !validSignature(s.config.Secret, payload, r.Header.Get(signatureHeader)) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	name := r.Header.Get("X-GitHub-Event")
+	delivery := r.Header.Get("X-GitHub-Delivery")
+	if name == "ping" {
+		_, _ = io.WriteString(w, "pong\n")
+		return
+	}
+
+	event, err := bot.ParseEvent(name, payload, s.config.LabelPrefix)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if event == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	job, created, err := s.jobs.add(delivery, *event)
+	if err != nil {
+		s.logger.WithError(err).Failf("error queueing the %s event", name)
+		http.Error(w, "error queueing the job", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		s.logger.WithField("job", job.ID).Infof("queued backports of %s#%d", event.Repo.NameWithOwner(), event.PRNumber)
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, job)
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	_, _ = io.WriteString(w, "ok\n")
+}
+
+// handleJobs lists the jobs as a page, or as JSON for ?format=json.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := s.jobs.list()
+	if r.URL.Query().Get("format") == "json" {
+		writeJSON(w, http.StatusOK, jobs)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := jobsPage.Execute(w, jobs); err != nil {
+		s.logger.WithError(err).Warnf("error rendering the jobs page")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
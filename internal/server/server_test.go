@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/134130/gh-cherry-pick/internal/bot"
+)
+
+const testSecret = "It's a Secret to Everybody"
+
+const backportComment = `{
+	"action": "created",
+	"issue": {"number": 12, "pull_request": {}},
+	"comment": {"id": 34, "body": "/backport release/1.2", "user": {"login": "octocat"}},
+	"repository": {"name": "repo", "owner": {"login": "owner"}, "html_url": "https://github.com/owner/repo"}
+}`
+
+// deliver sends a webhook signed the way GitHub does.
+func deliver(t *testing.T, url, event, delivery, payload, secret string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url+"/webhook", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", delivery)
+	req.Header.Set(signatureHeader, sign(secret, []byte(payload)))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	return resp
+}
+
+func TestWebhook(t *testing.T) {
+	s, err := New(Config{Secret: testSecret, DataDir: t.TempDir(), Workers: 2, LabelPrefix: "backport "})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processed := make(chan bot.Event, 1)
+	s.process = func(ctx context.Context, job *Job) ([]bot.Result, error) {
+		processed <- job.Event
+		return []bot.Result{{Target: "release/1.2", PullRequestURL: "https://github.com/owner/repo/pull/13"}}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wait := s.start(ctx)
+	defer wait()
+	defer cancel()
+
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	testcases := []struct {
+		name     string
+		event    string
+		delivery string
+		payload  string
+		secret   string
+		expected int
+	}{{
+		name:     "invalid signature",
+		event:    "issue_comment",
+		payload:  backportComment,
+		secret:   "wrong secret",
+		expected: http.StatusUnauthorized,
+	}, {
+		name:     "ping",
+		event:    "ping",
+		payload:  `{"zen": "Keep it logically awesome."}`,
+		secret:   testSecret,
+		expected: http.StatusOK,
+	}, {
+		name:     "no backport requested",
+		event:    "issue_comment",
+		payload:  `{"action": "created", "issue": {"number": 12, "pull_request": {}}, "comment": {"body": "LGTM"}}`,
+		secret:   testSecret,
+		expected: http.StatusNoContent,
+	}, {
+		name:     "backport command",
+		event:    "issue_comment",
+		delivery: "delivery-1",
+		payload:  backportComment,
+		secret:   testSecret,
+		expected: http.StatusAccepted,
+	}, {
+		name:     "redelivery",
+		event:    "issue_comment",
+		delivery: "delivery-1",
+		payload:  backportComment,
+		secret:   testSecret,
+		expected: http.StatusOK,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := deliver(t, ts.URL, tc.event, tc.delivery, tc.payload, tc.secret)
+			if resp.StatusCode != tc.expected {
+				t.Errorf("expected status %d, got %d", tc.expected, resp.StatusCode)
+			}
+		})
+	}
+
+	select {
+	case event := <-processed:
+		if event.PRNumber != 12 || !reflect.DeepEqual(event.Targets, []string{"release/1.2"}) {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job was not processed")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(ts.URL + "/jobs?format=json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var jobs []Job
+		err = json.NewDecoder(resp.Body).Decode(&jobs)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(jobs) != 1 {
+			t.Fatalf("expected 1 job, got %d", len(jobs))
+		}
+		if jobs[0].Status == JobSucceeded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the job to succeed, got %s", jobs[0].Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobStoreRequeuesOnRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	jobs, err := openJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, _, _ := jobs.add("delivery-1", bot.Event{PRNumber: 1})
+	queued, _, _ := jobs.add("delivery-2", bot.Event{PRNumber: 2})
+	done, _, _ := jobs.add("delivery-3", bot.Event{PRNumber: 3})
+	if _, err = jobs.next(); err != nil {
+		t.Fatal(err)
+	}
+	if err = jobs.finish(done.ID, JobSucceeded, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, err := openJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{running.ID, queued.ID}
+	if !reflect.DeepEqual(restarted.queue, expected) {
+		t.Errorf("expected queue %v, got %v", expected, restarted.queue)
+	}
+}
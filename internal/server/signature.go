@@ -0,0 +1,26 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const signatureHeader = "X-Hub-Signature-256"
+
+// sign returns the X-Hub-Signature-256 header GitHub sends with a payload.
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validSignature reports whether signature is the HMAC of the payload with
+// the webhook secret, comparing in constant time.
+func validSignature(secret string, payload []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(sign(secret, payload)), []byte(signature))
+}
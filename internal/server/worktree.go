@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/134130/gh-cherry-pick/git"
+	"github.com/134130/gh-cherry-pick/gitobj"
+	"github.com/134130/gh-cherry-pick/internal/bot"
+	"github.com/134130/gh-cherry-pick/internal/log"
+)
+
+// runInWorktree runs the job in a new worktree of the clone of its
+// repository, so that jobs running at the same time do not share a checkout.
+func (s *Server) runInWorktree(ctx context.Context, job *Job) ([]bot.Result, error) {
+	logger := log.LoggerFromCtx(ctx)
+
+	repoDir, repoMu, err := s.prepareClone(ctx, logger, job.Event.Repo)
+	if err != nil {
+		return nil, err
+	}
+	// The jobs of a repository fetch into and push from the same clone, and
+	// track their branches in its config, which fails when two update the
+	// same ref or the config at the same time.
+	ctx = git.CtxWithSharedLock(ctx, repoMu)
+	repoCtx := git.CtxWithDir(ctx, repoDir)
+
+	dir := filepath.Join(s.config.DataDir, "worktrees", job.ID)
+	if _, err = os.Stat(dir); err == nil {
+		// Left behind by a job interrupted by a crash.
+		_ = git.RemoveWorktree(repoCtx, dir)
+		_ = os.RemoveAll(dir)
+		_ = git.PruneWorktrees(repoCtx)
+	}
+
+	logger.WithField("worktree", dir).Infof("creating worktree")
+	if err = git.AddWorktree(repoCtx, dir, "HEAD"); err != nil {
+		return nil, fmt.Errorf("error creating the worktree: %w", err)
+	}
+	defer func() {
+		if err := git.RemoveWorktree(context.WithoutCancel(repoCtx), dir); err != nil {
+			logger.WithError(err).Warnf("error removing the worktree")
+		}
+	}()
+
+	return bot.HandleEvent(git.CtxWithDir(ctx, dir), &job.Event, bot.Options{
+		MergeStrategy: s.config.MergeStrategy,
+		Metadata:      bot.DefaultMetadata(s.config.LabelPrefix),
+		OnConflict:    s.config.OnConflict,
+	})
+}
+
+// prepareClone returns the clone of the repository, cloning it on first use,
+// and its lock. Jobs of the same repository wait for each other while it is
+// cloned, and while they update its refs and config.
+func (s *Server) prepareClone(ctx context.Context, logger log.Logger, repo gitobj.Repository) (string, *sync.Mutex, error) {
+	dir := filepath.Join(s.config.DataDir, "repos", repo.Host, repo.Owner, repo.Name)
+
+	s.reposMu.Lock()
+	mu, ok := s.repos[dir]
+	if !ok {
+		mu = &sync.Mutex{}
+		s.repos[dir] = mu
+	}
+	s.reposMu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return dir, mu, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", nil, fmt.Errorf("error creating the clone directory: %w", err)
+	}
+	logger.Infof("cloning %s to %s", repo.NameWithOwner(), dir)
+	if err := git.Clone(ctx, repo.CloneURL(), dir); err != nil {
+		return "", nil, fmt.Errorf("error cloning %s: %w", repo.NameWithOwner(), err)
+	}
+	return dir, mu, nil
+}
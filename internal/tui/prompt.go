@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	internalColor "github.com/134130/gh-cherry-pick/internal/color"
+)
+
+// IsInteractive reports whether stdin is a terminal, which can be prompted.
+func IsInteractive() bool {
+	return isTerminal(os.Stdin)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Confirm asks a yes or no question on the terminal, defaulting to no.
+func Confirm(question string) (bool, error) {
+	fmt.Fprintf(os.Stdout, "%s %s ", internalColor.Bold(question), internalColor.Grey("[y/N]"))
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("error reading the answer: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
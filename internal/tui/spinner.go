@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/briandowns/spinner"
@@ -21,7 +22,7 @@ func WithStep(ctx context.Context, title string, f func(ctx context.Context, log
 
 	err = f(ctx, logger)
 
-	_, _ = fmt.Fprintln(os.Stdout)
+	_, _ = fmt.Fprintln(log.WriterFromCtx(ctx))
 
 	return
 }
@@ -30,7 +31,7 @@ func WithSpinner(ctx context.Context, title string, f func(ctx context.Context,
 	logger := log.LoggerFromCtx(ctx)
 	logger.IncreaseIndent()
 
-	sp := spinner.New(spinner.CharSets[14], 40*time.Millisecond, spinner.WithColor("cyan"))
+	sp := newSpinner(ctx)
 	sp.Suffix = " " + title
 	sp.FinalMSG = fmt.Sprintf("%s %s\n", internalColor.Green("✔"), title)
 	sp.Start()
@@ -49,3 +50,85 @@ func WithSpinner(ctx context.Context, title string, f func(ctx context.Context,
 
 	return
 }
+
+// WithSpinners runs f for each of the titles, up to jobs at the same time,
+// with a spinner while they run. Each title is logged as it finishes. It
+// returns the errors of f in the order of the titles.
+func WithSpinners(ctx context.Context, titles []string, jobs int, f func(ctx context.Context, i int) error) []error {
+	logger := log.LoggerFromCtx(ctx)
+
+	var mu sync.Mutex
+	running, done := 0, 0
+	sp := newSpinner(ctx)
+	update := func() {
+		sp.Lock()
+		defer sp.Unlock()
+		sp.Suffix = fmt.Sprintf(" %d running, %d of %d done", running, done, len(titles))
+	}
+	update()
+	sp.Start()
+	// finish logs the title of f, with the spinner stopped not to draw over it.
+	finish := func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sp.Stop()
+		if err != nil {
+			logger.Failf(titles[i])
+		} else {
+			logger.Successf(titles[i])
+		}
+		done++
+		update()
+		sp.Start()
+	}
+
+	errs := make([]error, len(titles))
+	slots := make(chan struct{}, max(jobs, 1))
+	var wg sync.WaitGroup
+	for i := range titles {
+		slots <- struct{}{}
+		if errs[i] = ctx.Err(); errs[i] != nil {
+			<-slots
+			finish(i, errs[i])
+			continue
+		}
+
+		mu.Lock()
+		running++
+		update()
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			errs[i] = f(ctx, i)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			finish(i, errs[i])
+		}()
+	}
+	wg.Wait()
+
+	sp.Stop()
+	return errs
+}
+
+// newSpinner returns a spinner which draws to the writer of the logger of
+// ctx. It spins only on a terminal.
+func newSpinner(ctx context.Context) *spinner.Spinner {
+	w := log.WriterFromCtx(ctx)
+	f, isFile := w.(*os.File)
+	option := spinner.WithWriter(w)
+	if isFile {
+		option = spinner.WithWriterFile(f)
+	}
+
+	sp := spinner.New(spinner.CharSets[14], 40*time.Millisecond, spinner.WithColor("cyan"), option)
+	if !isFile {
+		// The terminal check of the spinner is on stdout then.
+		sp.Disable()
+	}
+	return sp
+}